@@ -0,0 +1,352 @@
+package gocosmosdb
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// blockingStage - reads every result of its source before yielding the results computed from them, eg. aggregates.
+// Its continuation token restarts the ranges and skips the results already returned.
+type blockingStage struct {
+	source   querySource
+	initial  []rangeState
+	compute  func(source querySource) ([]json.RawMessage, error)
+	results  []json.RawMessage
+	pos      int
+	computed bool
+}
+
+// newBlockingStage - creates a blocking stage resuming from token
+func newBlockingStage(source querySource, token *crossPartitionToken, compute func(source querySource) ([]json.RawMessage, error)) *blockingStage {
+	return &blockingStage{
+		source:  source,
+		initial: append([]rangeState{}, token.Ranges...),
+		compute: compute,
+		pos:     token.Emitted,
+	}
+}
+
+func (b *blockingStage) peek() (json.RawMessage, bool, error) {
+	if !b.computed {
+		results, err := b.compute(b.source)
+		if err != nil {
+			return nil, false, err
+		}
+		b.results, b.computed = results, true
+	}
+	if b.pos >= len(b.results) {
+		return nil, false, nil
+	}
+	return b.results[b.pos], true, nil
+}
+
+func (b *blockingStage) next() (json.RawMessage, bool, error) {
+	raw, ok, err := b.peek()
+	if ok {
+		b.pos++
+	}
+	return raw, ok, err
+}
+
+func (b *blockingStage) state(token *crossPartitionToken) {
+	token.Ranges, token.Emitted = b.initial, b.pos
+}
+
+// aggregator - combines the partial aggregates of the partition key ranges
+type aggregator interface {
+	// add - adds a partial aggregate, nil when it is undefined
+	add(item json.RawMessage) error
+	// result - returns the aggregate, false when it is undefined
+	result() (interface{}, bool)
+}
+
+// newAggregator - creates the aggregator of an aggregate of the query plan
+func newAggregator(kind string) (aggregator, error) {
+	switch kind {
+	case "Count":
+		return &sumAggregator{count: true}, nil
+	case "Sum":
+		return &sumAggregator{}, nil
+	case "Average":
+		return &averageAggregator{}, nil
+	case "Min":
+		return &minMaxAggregator{key: "min", sign: -1}, nil
+	case "Max":
+		return &minMaxAggregator{key: "max", sign: 1}, nil
+	}
+	return nil, &ValidationError{fmt.Sprintf("cross-partition %s aggregates are not supported", kind)}
+}
+
+// sumAggregator - adds up counts or sums, a sum is undefined when any partial sum is
+type sumAggregator struct {
+	count     bool
+	sum       float64
+	undefined bool
+}
+
+func (a *sumAggregator) add(item json.RawMessage) error {
+	if item == nil {
+		a.undefined = a.undefined || !a.count
+		return nil
+	}
+	var v float64
+	if err := json.Unmarshal(item, &v); err != nil {
+		return err
+	}
+	a.sum += v
+	return nil
+}
+
+func (a *sumAggregator) result() (interface{}, bool) {
+	return a.sum, !a.undefined
+}
+
+// averageAggregator - divides the total of the partial sums by the total of their counts
+type averageAggregator struct {
+	sum       float64
+	count     float64
+	undefined bool
+}
+
+func (a *averageAggregator) add(item json.RawMessage) error {
+	if item == nil {
+		return nil
+	}
+	partial := struct {
+		Sum   *float64 `json:"sum"`
+		Count float64  `json:"count"`
+	}{}
+	if err := json.Unmarshal(item, &partial); err != nil {
+		return err
+	}
+	if partial.Count == 0 {
+		return nil
+	}
+	if partial.Sum == nil {
+		// the partition averaged values that are not numbers
+		a.undefined = true
+		return nil
+	}
+	a.sum += *partial.Sum
+	a.count += partial.Count
+	return nil
+}
+
+func (a *averageAggregator) result() (interface{}, bool) {
+	if a.undefined || a.count == 0 {
+		return nil, false
+	}
+	return a.sum / a.count, true
+}
+
+// minMaxAggregator - keeps the lowest or highest partial value, undefined partial values are ignored.
+// Partial values come either as is or as an object with the value under key and the count of values.
+type minMaxAggregator struct {
+	key     string
+	sign    int
+	value   interface{}
+	defined bool
+}
+
+func (a *minMaxAggregator) add(item json.RawMessage) error {
+	if item == nil {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal(item, &v); err != nil {
+		return err
+	}
+	if obj, ok := v.(map[string]interface{}); ok {
+		if count, ok := obj["count"].(float64); ok {
+			value, defined := obj[a.key]
+			if count == 0 || !defined {
+				return nil
+			}
+			v = value
+		}
+	}
+	if !a.defined || compareValues(v, a.value)*a.sign > 0 {
+		a.value, a.defined = v, true
+	}
+	return nil
+}
+
+func (a *minMaxAggregator) result() (interface{}, bool) {
+	return a.value, a.defined
+}
+
+// aggregateItem - a partial aggregate computed by a partition key range, Item is nil when it is undefined
+type aggregateItem struct {
+	Item json.RawMessage `json:"item"`
+}
+
+// aggregate - combines the partial results of a SELECT VALUE aggregate query, every partition key range returns
+// its partial aggregates as an array of items
+func (qi queryInfo) aggregate(source querySource) ([]json.RawMessage, error) {
+	aggregators := make([]aggregator, len(qi.Aggregates))
+	for i, kind := range qi.Aggregates {
+		var err error
+		if aggregators[i], err = newAggregator(kind); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		raw, ok, err := source.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		items := []aggregateItem{}
+		if err = json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			if i < len(aggregators) {
+				if err = aggregators[i].add(item.Item); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	results := []json.RawMessage{}
+	for _, a := range aggregators {
+		if v, ok := a.result(); ok {
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, data)
+		}
+	}
+	return results, nil
+}
+
+// group - the aggregates and values of a group of a GROUP BY query
+type group struct {
+	aggregators map[string]aggregator
+	values      map[string]json.RawMessage
+}
+
+// groupBy - groups the partial results of a GROUP BY query, or of a query selecting aggregates without VALUE, by
+// their group by items and combines the aggregates of every group. Every result is an object with the group by
+// items and a payload holding the aliases of the query, an aggregate alias holds a partial aggregate item.
+func (qi queryInfo) groupBy(source querySource) ([]json.RawMessage, error) {
+	groups := map[string]*group{}
+	order := []string{}
+	aliases := qi.GroupByAliases
+	if len(aliases) == 0 {
+		for alias := range qi.GroupByAliasToAggregateType {
+			aliases = append(aliases, alias)
+		}
+	}
+	for {
+		raw, ok, err := source.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		result := struct {
+			GroupByItems json.RawMessage `json:"groupByItems"`
+			Payload      json.RawMessage `json:"payload"`
+		}{}
+		if err = json.Unmarshal(raw, &result); err != nil {
+			return nil, err
+		}
+		key := string(result.GroupByItems)
+		g, ok := groups[key]
+		if !ok {
+			g = &group{aggregators: map[string]aggregator{}, values: map[string]json.RawMessage{}}
+			for alias, kind := range qi.GroupByAliasToAggregateType {
+				if kind == nil {
+					continue
+				}
+				if g.aggregators[alias], err = newAggregator(*kind); err != nil {
+					return nil, err
+				}
+			}
+			groups[key] = g
+			order = append(order, key)
+		}
+		if qi.HasSelectValue {
+			if err = g.addValue(aliases, result.Payload); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		payload := map[string]json.RawMessage{}
+		if err = json.Unmarshal(result.Payload, &payload); err != nil {
+			return nil, err
+		}
+		for alias, value := range payload {
+			if a, ok := g.aggregators[alias]; ok {
+				item := aggregateItem{}
+				if err = json.Unmarshal(value, &item); err != nil {
+					return nil, err
+				}
+				if err = a.add(item.Item); err != nil {
+					return nil, err
+				}
+			} else if _, ok := g.values[alias]; !ok {
+				g.values[alias] = value
+			}
+		}
+	}
+	results := []json.RawMessage{}
+	for _, key := range order {
+		data, err := groups[key].result(aliases, qi.HasSelectValue)
+		if err != nil {
+			return nil, err
+		}
+		if data != nil {
+			results = append(results, data)
+		}
+	}
+	return results, nil
+}
+
+// addValue - adds the payload of a SELECT VALUE query, either a partial aggregate item or a value
+func (g *group) addValue(aliases []string, payload json.RawMessage) error {
+	if len(aliases) > 0 {
+		if a, ok := g.aggregators[aliases[0]]; ok {
+			item := aggregateItem{}
+			if err := json.Unmarshal(payload, &item); err != nil {
+				return err
+			}
+			return a.add(item.Item)
+		}
+	}
+	if _, ok := g.values[""]; !ok {
+		g.values[""] = payload
+	}
+	return nil
+}
+
+// result - returns the payload of a group, nil when a selected value is undefined
+func (g *group) result(aliases []string, selectValue bool) (json.RawMessage, error) {
+	if selectValue {
+		if len(aliases) > 0 {
+			if a, ok := g.aggregators[aliases[0]]; ok {
+				v, defined := a.result()
+				if !defined {
+					return nil, nil
+				}
+				return json.Marshal(v)
+			}
+		}
+		return g.values[""], nil
+	}
+	payload := map[string]interface{}{}
+	for alias, value := range g.values {
+		payload[alias] = value
+	}
+	for alias, a := range g.aggregators {
+		if v, defined := a.result(); defined {
+			payload[alias] = v
+		}
+	}
+	return json.Marshal(payload)
+}
@@ -0,0 +1,124 @@
+package gocosmosdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func valuePlan(aggregate string) string {
+	return fmt.Sprintf(`{"queryInfo": {"distinctType": "None", "aggregates": ["%s"], "hasSelectValue": true,
+		"rewrittenQuery": "SELECT VALUE [{\"item\": AGG(c.n)}] FROM c"}}`, aggregate)
+}
+
+func TestCrossPartitionAggregates(t *testing.T) {
+	assert := assert.New(t)
+	cases := []struct {
+		aggregate string
+		partials  []string
+		expected  []float64
+	}{
+		{"Count", []string{`[{"item": 3}]`, `[{"item": 0}]`, `[{"item": 4}]`}, []float64{7}},
+		{"Sum", []string{`[{"item": 1.5}]`, `[{"item": 2}]`, `[{"item": 0}]`}, []float64{3.5}},
+		{"Sum", []string{`[{"item": 1.5}]`, `[{}]`, `[{"item": 0}]`}, []float64{}},
+		{"Average", []string{`[{"item": {"sum": 10, "count": 2}}]`, `[{"item": {"sum": 0, "count": 0}}]`, `[{"item": {"sum": 5, "count": 3}}]`}, []float64{3}},
+		{"Average", []string{`[{"item": {"count": 0}}]`, `[{"item": {"count": 0}}]`, `[{"item": {"count": 0}}]`}, []float64{}},
+		{"Min", []string{`[{"item": 4}]`, `[{}]`, `[{"item": {"min": 2, "count": 1}}]`}, []float64{2}},
+		{"Max", []string{`[{"item": {"max": 9, "count": 0}}]`, `[{"item": 4}]`, `[{"item": {"max": 7, "count": 2}}]`}, []float64{7}},
+		{"Max", []string{`[{}]`, `[{}]`, `[{}]`}, []float64{}},
+	}
+	for _, c := range cases {
+		s := newPartitionedServer(valuePlan(c.aggregate), map[string][]string{
+			"0": {c.partials[0]},
+			"1": {c.partials[1]},
+			"2": {c.partials[2]},
+		})
+		client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+		query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", SelectValue("COUNT(1)").Build(), 10)
+		results := []float64{}
+		assert.Nil(query.Next(&results), c.aggregate)
+		assert.Equal(c.expected, results, "%s %v", c.aggregate, c.partials)
+		assert.True(query.Done())
+		s.Close()
+	}
+}
+
+const groupByPlan = `{"queryInfo": {"distinctType": "None", "groupByExpressions": ["c.team"], "groupByAliases": ["team", "n", "avg", "low"],
+	"groupByAliasToAggregateType": {"team": null, "n": "Count", "avg": "Average", "low": "Min"},
+	"rewrittenQuery": "SELECT [{\"item\": c.team}] AS groupByItems, {...} AS payload FROM c GROUP BY c.team"}}`
+
+func groupByRow(team string, n int, sum float64, low string) string {
+	return fmt.Sprintf(`{"groupByItems": [{"item": "%s"}], "payload": {"team": "%s", "n": {"item": %d},
+		"avg": {"item": {"sum": %v, "count": %d}}, "low": {"item": %s}}}`, team, team, n, sum, n, low)
+}
+
+func TestCrossPartitionGroupBy(t *testing.T) {
+	assert := assert.New(t)
+	s := newPartitionedServer(groupByPlan, map[string][]string{
+		"0": {groupByRow("red", 2, 10, `{"min": 3, "count": 2}`), groupByRow("blue", 1, 1, "1")},
+		"1": {groupByRow("red", 1, 5, "2")},
+		"2": {groupByRow("green", 3, 3, `{"count": 0}`), groupByRow("blue", 1, 5, "7")},
+	})
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	type row struct {
+		Team string   `json:"team"`
+		N    int      `json:"n"`
+		Avg  float64  `json:"avg"`
+		Low  *float64 `json:"low"`
+	}
+	query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().Build(), 2)
+	rows := []row{}
+	assert.Nil(query.Next(&rows))
+	assert.Len(rows, 2)
+	assert.Equal("red", rows[0].Team)
+	assert.Equal(3, rows[0].N)
+	assert.Equal(5.0, rows[0].Avg)
+	assert.Equal(2.0, *rows[0].Low)
+	assert.Equal("blue", rows[1].Team)
+	assert.Equal(3.0, rows[1].Avg)
+	assert.False(query.Done())
+
+	// the last group is returned by a query resumed from the continuation
+	continuation, err := query.Continuation()
+	assert.Nil(err)
+	query = client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().Build(), 2).SetContinuation(continuation)
+	rows = []row{}
+	assert.Nil(query.Next(&rows))
+	assert.Len(rows, 1)
+	assert.Equal("green", rows[0].Team)
+	assert.Nil(rows[0].Low)
+	assert.True(query.Done())
+}
+
+func TestCrossPartitionGroupBySelectValue(t *testing.T) {
+	assert := assert.New(t)
+	plan := `{"queryInfo": {"groupByExpressions": ["c.team"], "groupByAliases": ["$1"], "groupByAliasToAggregateType": {"$1": "Sum"},
+		"hasSelectValue": true, "rewrittenQuery": "SELECT [{\"item\": c.team}] AS groupByItems, {\"item\": SUM(c.n)} AS payload FROM c GROUP BY c.team"}}`
+	s := newPartitionedServer(plan, map[string][]string{
+		"0": {`{"groupByItems": [{"item": "a"}], "payload": {"item": 2}}`},
+		"1": {`{"groupByItems": [{"item": "a"}], "payload": {"item": 3}}`, `{"groupByItems": [{"item": "b"}], "payload": {"item": 1}}`},
+	})
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().Build(), 0)
+	sums := []float64{}
+	assert.Nil(query.Next(&sums))
+	assert.Equal([]float64{5, 1}, sums)
+}
+
+func TestMinMaxAggregatorTypes(t *testing.T) {
+	assert := assert.New(t)
+	a, err := newAggregator("Max")
+	assert.Nil(err)
+	for _, item := range []string{`1`, `"a"`, `true`} {
+		assert.Nil(a.add(json.RawMessage(item)))
+	}
+	v, ok := a.result()
+	assert.True(ok)
+	assert.Equal("a", v)
+	_, err = newAggregator("CountIf")
+	assert.True(IsValidationError(err))
+}
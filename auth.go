@@ -1,12 +1,150 @@
 package gocosmosdb
 
 import (
+	"bufio"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
 	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
 )
 
+// KeyProvider - supplies the primary and secondary master keys used to sign requests
+type KeyProvider interface {
+	MasterKeys() (primary, secondary string, err error)
+}
+
+// FileKeyProvider - a KeyProvider that reads the master keys from a file on disk.
+// The first non-empty line of the file is the primary key and the second is the secondary key.
+// The file is reloaded whenever its modification time changes.
+type FileKeyProvider struct {
+	path      string
+	mu        sync.Mutex
+	modTime   time.Time
+	primary   string
+	secondary string
+}
+
+// NewFileKeyProvider - creates a FileKeyProvider that reads keys from the passed path
+func NewFileKeyProvider(path string) *FileKeyProvider {
+	return &FileKeyProvider{path: path}
+}
+
+// MasterKeys - returns the keys from the file, reloading it if it has changed
+func (p *FileKeyProvider) MasterKeys() (string, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return "", "", err
+	}
+	if info.ModTime().Equal(p.modTime) && p.primary != "" {
+		return p.primary, p.secondary, nil
+	}
+	f, err := os.Open(p.path)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+	var keys []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() && len(keys) < 2 {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			keys = append(keys, line)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return "", "", err
+	}
+	if len(keys) == 0 {
+		return "", "", errors.New("no master key found in " + p.path)
+	}
+	p.primary, p.secondary = keys[0], ""
+	if len(keys) > 1 {
+		p.secondary = keys[1]
+	}
+	p.modTime = info.ModTime()
+	return p.primary, p.secondary, nil
+}
+
+// AccessToken - a bearer token and the time it expires
+type AccessToken struct {
+	Token     string
+	ExpiresOn time.Time
+}
+
+// TokenCredential - supplies bearer tokens used to authorize requests with Azure Active Directory
+type TokenCredential interface {
+	GetToken(ctx context.Context) (AccessToken, error)
+}
+
+// StaticTokenCredential - a TokenCredential that always returns the same token
+type StaticTokenCredential struct {
+	token AccessToken
+}
+
+// NewStaticTokenCredential - creates a TokenCredential for a token obtained elsewhere
+func NewStaticTokenCredential(token string, expiresOn time.Time) *StaticTokenCredential {
+	return &StaticTokenCredential{AccessToken{Token: token, ExpiresOn: expiresOn}}
+}
+
+// GetToken - returns the static token
+func (c *StaticTokenCredential) GetToken(ctx context.Context) (AccessToken, error) {
+	return c.token, nil
+}
+
+// defaultTokenRefreshBefore - how long before expiry a cached token is refreshed
+const defaultTokenRefreshBefore = 5 * time.Minute
+
+// tokenCache - caches the tokens of a TokenCredential and refreshes them before they expire
+type tokenCache struct {
+	credential    TokenCredential
+	refreshBefore time.Duration
+	mu            sync.Mutex
+	token         AccessToken
+}
+
+func newTokenCache(credential TokenCredential, refreshBefore time.Duration) *tokenCache {
+	if refreshBefore <= 0 {
+		refreshBefore = defaultTokenRefreshBefore
+	}
+	return &tokenCache{credential: credential, refreshBefore: refreshBefore}
+}
+
+// get - returns the cached token, refreshing it if it is about to expire.
+// If the refresh fails but the cached token is still valid it is returned.
+func (t *tokenCache) get(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if t.token.Token != "" && now.Add(t.refreshBefore).Before(t.token.ExpiresOn) {
+		return t.token.Token, nil
+	}
+	token, err := t.credential.GetToken(ctx)
+	if err != nil {
+		if t.token.Token != "" && now.Before(t.token.ExpiresOn) {
+			return t.token.Token, nil
+		}
+		return "", err
+	}
+	if token.Token == "" {
+		return "", errors.New("token credential returned an empty token")
+	}
+	t.token = token
+	return t.token.Token, nil
+}
+
+// invalidate - forces the next get to fetch a new token
+func (t *tokenCache) invalidate() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.token = AccessToken{}
+}
+
 func authorize(str, key string) (string, error) {
 	var ret string
 	enc := base64.StdEncoding
@@ -14,7 +152,7 @@ func authorize(str, key string) (string, error) {
 
 	if err != nil {
 		if _, ok := err.(base64.CorruptInputError); ok {
-			err = errors.New("base64 input is corrupt, check CosmosDB key")
+			err = &ValidationError{"base64 input is corrupt, check CosmosDB key"}
 			return ret, err
 		}
 		return ret, err
@@ -0,0 +1,127 @@
+package gocosmosdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileKeyProvider(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "gocosmosdb")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "keys")
+
+	provider := NewFileKeyProvider(keyFile)
+	_, _, err = provider.MasterKeys()
+	assert.NotNil(err)
+
+	assert.Nil(ioutil.WriteFile(keyFile, []byte("primary\n\nsecondary\n"), 0600))
+	primary, secondary, err := provider.MasterKeys()
+	assert.Nil(err)
+	assert.Equal("primary", primary)
+	assert.Equal("secondary", secondary)
+
+	// rotate the keys on disk
+	assert.Nil(ioutil.WriteFile(keyFile, []byte("secondary\nnewkey\n"), 0600))
+	later := time.Now().Add(time.Minute)
+	assert.Nil(os.Chtimes(keyFile, later, later))
+	primary, secondary, err = provider.MasterKeys()
+	assert.Nil(err)
+	assert.Equal("secondary", primary)
+	assert.Equal("newkey", secondary)
+
+	assert.Nil(ioutil.WriteFile(keyFile, []byte("\n"), 0600))
+	later = later.Add(time.Minute)
+	assert.Nil(os.Chtimes(keyFile, later, later))
+	_, _, err = provider.MasterKeys()
+	assert.Contains(err.Error(), "no master key found")
+}
+
+func TestKeyProviderSignsRequests(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "gocosmosdb")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "keys")
+	assert.Nil(ioutil.WriteFile(keyFile, []byte("YXJpZWwNCg==\n"), 0600))
+
+	s := ServerFactory(`{"id": "iot2"}`)
+	defer s.Close()
+	client := New(s.URL, Config{KeyProvider: NewFileKeyProvider(keyFile)}, log)
+	db, err := client.ReadDatabase("dbs/qicAAA==")
+	assert.Nil(err)
+	assert.Equal("iot2", db.Id)
+	s.AssertHeaders(t, HeaderAuth)
+}
+
+type countingCredential struct {
+	calls int
+	ttl   time.Duration
+	err   error
+}
+
+func (c *countingCredential) GetToken(ctx context.Context) (AccessToken, error) {
+	c.calls++
+	if c.err != nil {
+		return AccessToken{}, c.err
+	}
+	return AccessToken{Token: fmt.Sprintf("token%d", c.calls), ExpiresOn: time.Now().Add(c.ttl)}, nil
+}
+
+func TestTokenCache(t *testing.T) {
+	assert := assert.New(t)
+
+	// tokens outside of the refresh window are cached
+	cred := &countingCredential{ttl: time.Hour}
+	cache := newTokenCache(cred, 0)
+	token, err := cache.get(context.Background())
+	assert.Nil(err)
+	assert.Equal("token1", token)
+	token, err = cache.get(context.Background())
+	assert.Nil(err)
+	assert.Equal("token1", token)
+	assert.Equal(1, cred.calls)
+
+	// tokens inside of the refresh window are refreshed before they expire
+	cred = &countingCredential{ttl: time.Minute}
+	cache = newTokenCache(cred, 0)
+	token, _ = cache.get(context.Background())
+	assert.Equal("token1", token)
+	token, _ = cache.get(context.Background())
+	assert.Equal("token2", token)
+
+	// a failed refresh keeps using a token that has not expired yet
+	cred.err = errors.New("aad unavailable")
+	token, err = cache.get(context.Background())
+	assert.Nil(err)
+	assert.Equal("token2", token)
+
+	// invalidated tokens are not reused
+	cache.invalidate()
+	_, err = cache.get(context.Background())
+	assert.Contains(err.Error(), "aad unavailable")
+}
+
+func TestStaticTokenCredential(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(`{"id": "iot2"}`)
+	defer s.Close()
+	client := New(s.URL, Config{TokenCredential: NewStaticTokenCredential("aadtoken", time.Now().Add(time.Hour))}, log)
+	db, err := client.ReadDatabase("dbs/qicAAA==")
+	assert.Nil(err)
+	assert.Equal("iot2", db.Id)
+	assert.Equal("type%3Daad%26ver%3D1.0%26sig%3Daadtoken", s.Header.Get(HeaderAuth))
+
+	// the token cache is created with the client, master key clients have none
+	assert.NotNil(client.client.tokenCache())
+	assert.Nil(New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log).client.tokenCache())
+}
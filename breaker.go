@@ -0,0 +1,246 @@
+package gocosmosdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen - matched by errors.Is for requests rejected by an open circuit
+var ErrCircuitOpen = errors.New("circuit open")
+
+// CircuitBreakerConfig - configures the per endpoint and partition key range circuit breaker
+type CircuitBreakerConfig struct {
+	FailureThreshold int           // consecutive failures that open a circuit, defaults to 5
+	OpenTimeout      time.Duration // how long a circuit stays open before it is probed, defaults to 30 seconds
+	HalfOpenProbes   int           // successful probes that close a half open circuit, defaults to 1
+}
+
+// CircuitState - the state of a circuit
+type CircuitState int
+
+const (
+	// CircuitClosed - requests flow normally
+	CircuitClosed CircuitState = iota
+
+	// CircuitOpen - requests are rejected until the open timeout elapses
+	CircuitOpen
+
+	// CircuitHalfOpen - a single probe request at a time is let through
+	CircuitHalfOpen
+)
+
+// String - returns the name of the state
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// MarshalText - encodes the state by name
+func (s CircuitState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// CircuitStatus - a snapshot of a circuit for dashboards
+type CircuitStatus struct {
+	Endpoint          string       `json:"endpoint"`
+	PartitionKeyRange string       `json:"partitionKeyRange,omitempty"`
+	State             CircuitState `json:"state"`
+	Failures          int          `json:"failures"`
+	OpenedAt          time.Time    `json:"openedAt,omitempty"`
+}
+
+// CircuitOpenError - returned for requests rejected by an open circuit
+type CircuitOpenError struct {
+	Endpoint          string
+	PartitionKeyRange string
+	RetryAt           time.Time
+}
+
+// Error - implements the error interface
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for endpoint %s partition key range %q, retry at %s",
+		e.Endpoint, e.PartitionKeyRange, e.RetryAt.Format(time.RFC3339))
+}
+
+// Is - matches ErrCircuitOpen
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// circuitKey - identifies a circuit
+type circuitKey struct {
+	endpoint          string
+	partitionKeyRange string
+}
+
+// circuit - the state of one endpoint and partition key range
+type circuit struct {
+	state     CircuitState
+	failures  int
+	successes int
+	openedAt  time.Time
+	probing   bool
+}
+
+// circuitBreaker - tracks failures per endpoint and partition key range
+type circuitBreaker struct {
+	threshold int
+	timeout   time.Duration
+	probes    int
+	mu        sync.Mutex
+	circuits  map[circuitKey]*circuit
+}
+
+func newCircuitBreaker(conf *CircuitBreakerConfig) *circuitBreaker {
+	b := &circuitBreaker{
+		threshold: conf.FailureThreshold,
+		timeout:   conf.OpenTimeout,
+		probes:    conf.HalfOpenProbes,
+		circuits:  map[circuitKey]*circuit{},
+	}
+	if b.threshold <= 0 {
+		b.threshold = 5
+	}
+	if b.timeout <= 0 {
+		b.timeout = 30 * time.Second
+	}
+	if b.probes <= 0 {
+		b.probes = 1
+	}
+	return b
+}
+
+// circuitKeyFor - keys a request by host and the partition key range it targets. When the range is
+// not known the partition key is used, since a partition key always maps to a single range.
+func circuitKeyFor(r *Request) circuitKey {
+	key := circuitKey{endpoint: r.URL.Host}
+	if id := r.Header.Get(HeaderPartitionKeyRangeID); id != "" {
+		key.partitionKeyRange = id
+	} else {
+		key.partitionKeyRange = r.Header.Get(HeaderPartitionKey)
+	}
+	return key
+}
+
+// allow - returns an error if the circuit for key is open
+func (b *circuitBreaker) allow(key circuitKey) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[key]
+	if !ok {
+		return nil
+	}
+	switch c.state {
+	case CircuitOpen:
+		if time.Since(c.openedAt) < b.timeout {
+			return &CircuitOpenError{key.endpoint, key.partitionKeyRange, c.openedAt.Add(b.timeout)}
+		}
+		c.state = CircuitHalfOpen
+		c.successes = 0
+		c.probing = true
+	case CircuitHalfOpen:
+		if c.probing {
+			return &CircuitOpenError{key.endpoint, key.partitionKeyRange, time.Now()}
+		}
+		c.probing = true
+	}
+	return nil
+}
+
+// record - records the outcome of a request allowed through the circuit for key
+func (b *circuitBreaker) record(key circuitKey, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, ok := b.circuits[key]
+	if !ok {
+		if !failed {
+			return
+		}
+		c = &circuit{}
+		b.circuits[key] = c
+	}
+	c.probing = false
+	if failed {
+		c.failures++
+		if c.state == CircuitHalfOpen || c.failures >= b.threshold {
+			c.state = CircuitOpen
+			c.openedAt = time.Now()
+		}
+		return
+	}
+	if c.state == CircuitHalfOpen {
+		c.successes++
+		if c.successes < b.probes {
+			return
+		}
+	}
+	delete(b.circuits, key)
+}
+
+// release - ends a request allowed through the circuit for key without an outcome, eg. one canceled by the
+// caller. It frees the probe slot of a half open circuit and leaves the failure and success counts alone.
+func (b *circuitBreaker) release(key circuitKey) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c, ok := b.circuits[key]; ok {
+		c.probing = false
+	}
+}
+
+// status - returns a snapshot of every circuit that has seen failures
+func (b *circuitBreaker) status() []CircuitStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := make([]CircuitStatus, 0, len(b.circuits))
+	for key, c := range b.circuits {
+		state := c.state
+		if state == CircuitOpen && time.Since(c.openedAt) >= b.timeout {
+			state = CircuitHalfOpen
+		}
+		status = append(status, CircuitStatus{
+			Endpoint:          key.endpoint,
+			PartitionKeyRange: key.partitionKeyRange,
+			State:             state,
+			Failures:          c.failures,
+			OpenedAt:          c.openedAt,
+		})
+	}
+	sort.Slice(status, func(i, j int) bool {
+		if status[i].Endpoint != status[j].Endpoint {
+			return status[i].Endpoint < status[j].Endpoint
+		}
+		return status[i].PartitionKeyRange < status[j].PartitionKeyRange
+	})
+	return status
+}
+
+// isCircuitFailure - returns true for outcomes that count against a circuit: transport errors, timeouts and 503,
+// 408 and 429 responses. Other responses, eg. a 500 that exhausted its retries, are answers from a healthy endpoint.
+func isCircuitFailure(r *Request, resp *http.Response, err error) bool {
+	if err != nil {
+		// a request that ran out of time, including the client's own point, query and metadata timeouts, is
+		// the endpoint's fault, the caller giving up is not
+		return !isCallerCanceled(r)
+	}
+	switch resp.StatusCode {
+	case http.StatusServiceUnavailable, http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+// isCallerCanceled - returns true if the caller canceled the context of the request
+func isCallerCanceled(r *Request) bool {
+	return r.rContext != nil && errors.Is(r.rContext.Err(), context.Canceled)
+}
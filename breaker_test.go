@@ -0,0 +1,169 @@
+package gocosmosdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	assert := assert.New(t)
+	b := newCircuitBreaker(&CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: 20 * time.Millisecond})
+	key := circuitKey{"localhost", "0"}
+	other := circuitKey{"localhost", "1"}
+
+	assert.Nil(b.allow(key))
+	b.record(key, true)
+	assert.Nil(b.allow(key))
+	b.record(key, true)
+
+	// open after the threshold, other ranges are unaffected
+	err := b.allow(key)
+	assert.True(errors.Is(err, ErrCircuitOpen))
+	assert.Contains(err.Error(), `circuit open for endpoint localhost partition key range "0"`)
+	assert.Nil(b.allow(other))
+	assert.Equal(CircuitOpen, b.status()[0].State)
+
+	// half open after the timeout, allowing a single probe
+	time.Sleep(25 * time.Millisecond)
+	assert.Equal(CircuitHalfOpen, b.status()[0].State)
+	assert.Nil(b.allow(key))
+	assert.True(errors.Is(b.allow(key), ErrCircuitOpen))
+
+	// a failed probe opens the circuit again
+	b.record(key, true)
+	assert.True(errors.Is(b.allow(key), ErrCircuitOpen))
+
+	// a successful probe closes it
+	time.Sleep(25 * time.Millisecond)
+	assert.Nil(b.allow(key))
+	b.record(key, false)
+	assert.Nil(b.allow(key))
+	assert.Empty(b.status())
+}
+
+func TestCircuitStatusJSON(t *testing.T) {
+	assert := assert.New(t)
+	b, err := json.Marshal(CircuitStatus{Endpoint: "localhost", State: CircuitOpen, Failures: 5})
+	assert.Nil(err)
+	assert.Contains(string(b), `"state":"open"`)
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(http.StatusServiceUnavailable, http.StatusTooManyRequests, `{"id": "iot2"}`)
+	defer s.Close()
+	client := New(s.URL, Config{
+		MasterKey:      "YXJpZWwNCg==",
+		CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Hour},
+	}, log)
+
+	_, err := client.ReadDatabase("dbs/qicAAA==")
+	var reqErr *RequestError
+	assert.True(errors.As(err, &reqErr))
+	assert.Equal(http.StatusServiceUnavailable, reqErr.StatusCode)
+	_, err = client.ReadDatabase("dbs/qicAAA==")
+	assert.NotNil(err)
+	_, err = client.ReadDatabase("dbs/qicAAA==")
+	assert.True(errors.Is(err, ErrCircuitOpen))
+
+	status := client.CircuitBreakerStatus()
+	assert.Len(status, 1)
+	assert.Equal(CircuitOpen, status[0].State)
+	assert.Equal(2, status[0].Failures)
+	assert.Equal(s.Listener.Addr().String(), status[0].Endpoint)
+}
+
+func TestIsCircuitFailure(t *testing.T) {
+	assert := assert.New(t)
+	req, _ := http.NewRequest("GET", "http://localhost/dbs/db", nil)
+	r := ResourceRequest("dbs/db", req)
+	for status, failure := range map[int]bool{
+		http.StatusOK:                  false,
+		http.StatusNotFound:            false,
+		http.StatusInternalServerError: false,
+		http.StatusServiceUnavailable:  true,
+		http.StatusRequestTimeout:      true,
+		http.StatusTooManyRequests:     true,
+	} {
+		assert.Equal(failure, isCircuitFailure(r, &http.Response{StatusCode: status}, nil), status)
+	}
+	assert.True(isCircuitFailure(r, nil, errors.New("connection refused")))
+
+	// the caller giving up is not a failure of the endpoint
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.rContext = ctx
+	assert.False(isCircuitFailure(r, nil, ctx.Err()))
+
+	// running out of time, eg. the client's own metadata timeout, is
+	ctx, cancel = context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	r.rContext = ctx
+	assert.True(isCircuitFailure(r, nil, ctx.Err()))
+}
+
+func TestClientCircuitBreakerOpensOnTimeouts(t *testing.T) {
+	assert := assert.New(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-r.Context().Done():
+		}
+	}))
+	defer s.Close()
+	client := New(s.URL, Config{
+		MasterKey:       "YXJpZWwNCg==",
+		MetadataTimeout: 5 * time.Millisecond,
+		CircuitBreaker:  &CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Hour},
+	}, log)
+	for i := 0; i < 5; i++ {
+		client.ReadDatabase("dbs/qicAAA==")
+	}
+	status := client.CircuitBreakerStatus()
+	if assert.Len(status, 1) {
+		assert.Equal(CircuitOpen, status[0].State)
+	}
+	_, err := client.ReadDatabase("dbs/qicAAA==")
+	assert.True(errors.Is(err, ErrCircuitOpen))
+}
+
+func TestCircuitBreakerReleaseKeepsCounts(t *testing.T) {
+	assert := assert.New(t)
+	b := newCircuitBreaker(&CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Nanosecond})
+	key := circuitKey{"localhost", "0"}
+	b.record(key, true)
+	b.release(key)
+	assert.Equal(1, b.status()[0].Failures)
+	b.record(key, true)
+	time.Sleep(time.Millisecond)
+
+	// a canceled probe frees the probe slot without closing the circuit
+	assert.Nil(b.allow(key))
+	assert.Error(b.allow(key))
+	b.release(key)
+	assert.Nil(b.allow(key))
+	assert.Equal(CircuitHalfOpen, b.status()[0].State)
+}
+
+func TestClientCircuitBreakerIgnoresServerErrors(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(500, 500, 500)
+	defer s.Close()
+	client := New(s.URL, Config{
+		MasterKey:      "YXJpZWwNCg==",
+		CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 2, OpenTimeout: time.Hour},
+	}, log)
+	for i := 0; i < 3; i++ {
+		_, err := client.ReadDatabase("dbs/qicAAA==")
+		assert.False(errors.Is(err, ErrCircuitOpen))
+	}
+	assert.Empty(client.CircuitBreakerStatus())
+}
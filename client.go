@@ -2,30 +2,36 @@ package gocosmosdb
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"reflect"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/davecgh/go-spew/spew"
 	"github.com/hashicorp/go-cleanhttp"
 	"github.com/hashicorp/go-retryablehttp"
-	"github.com/intwinelabs/logger"
-	"github.com/moul/http2curl"
 )
 
 // Client - struct to hold the underlying SQL REST API client
 type apiClient struct {
-	uri        string
-	config     Config
-	httpClient *retryablehttp.Client
-	logger     *logger.Logger
+	uri          string
+	config       Config
+	httpClient   *retryablehttp.Client
+	logger       Logger
+	mu           sync.RWMutex
+	preferredKey string
+	tokens       *tokenCache
+	limiter      *ruLimiter
+	breaker      *circuitBreaker
 }
 
 func newAPIClient(conf *Config) *apiClient {
-	client := &apiClient{}
+	client := &apiClient{config: *conf}
 	httpClient := retryablehttp.NewClient()
 	httpClient.Logger = nil
 	client.httpClient = httpClient
@@ -41,18 +47,87 @@ func newAPIClient(conf *Config) *apiClient {
 		client.httpClient.RetryWaitMax = conf.RetryWaitMax
 	}
 	client.httpClient.RetryMax = conf.RetryMax
-	if conf.Pooled {
-		client.httpClient.HTTPClient.Transport = cleanhttp.DefaultPooledTransport()
+	// the last response of exhausted retries is returned, it becomes a RequestError with its status and diagnostics
+	client.httpClient.ErrorHandler = retryablehttp.PassthroughErrorHandler
+	if conf.HTTPClient != nil {
+		client.httpClient.HTTPClient = conf.HTTPClient
+	} else if transport := newTransport(conf); transport != nil {
+		client.httpClient.HTTPClient.Transport = transport
+	}
+	client.recordRetries()
+	if conf.TokenCredential != nil {
+		client.tokens = newTokenCache(conf.TokenCredential, conf.TokenRefreshBefore)
+	}
+	if conf.RULimit > 0 {
+		client.limiter = newRULimiter(conf.RULimit, conf.RUBurst)
+	}
+	if conf.CircuitBreaker != nil {
+		client.breaker = newCircuitBreaker(conf.CircuitBreaker)
 	}
 	return client
 }
 
-// apply - iterates over all opts and runs the functions to apply additional request headers
-func (c *apiClient) apply(r *Request, opts []CallOption) (err error) {
-	if err = r.DefaultHeaders(c.config.MasterKey); err != nil {
-		return err
+// recordRetries - hooks the retryable client to record retries in the diagnostics of each request
+func (c *apiClient) recordRetries() {
+	checkRetry := c.httpClient.CheckRetry
+	c.httpClient.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		retry, checkErr := checkRetry(ctx, resp, err)
+		if d := diagnosticsFromContext(ctx); d != nil && retry {
+			d.retrying(resp, err)
+		}
+		if t := traceFromContext(ctx); t != nil {
+			t.endAttempt(resp, err)
+		}
+		return retry, checkErr
+	}
+	c.httpClient.RequestLogHook = func(_ retryablehttp.Logger, req *http.Request, attempt int) {
+		if d := diagnosticsFromContext(req.Context()); d != nil && attempt > 0 {
+			d.retry()
+		}
+		if t := traceFromContext(req.Context()); t != nil {
+			t.startAttempt(req, attempt)
+		}
+	}
+}
+
+// newTransport - returns the transport for the default http client, nil keeps the default transport
+func newTransport(conf *Config) http.RoundTripper {
+	if conf.Transport != nil {
+		return conf.Transport
+	}
+	if !conf.Pooled && conf.Proxy == nil && conf.TLSConfig == nil && conf.DialTimeout == 0 && conf.DialKeepAlive == 0 {
+		return nil
+	}
+	var transport *http.Transport
+	if conf.Pooled {
+		transport = cleanhttp.DefaultPooledTransport()
+	} else {
+		transport = cleanhttp.DefaultTransport()
+	}
+	if conf.Proxy != nil {
+		transport.Proxy = conf.Proxy
+	}
+	if conf.TLSConfig != nil {
+		transport.TLSClientConfig = conf.TLSConfig
+	}
+	if conf.DialTimeout != 0 || conf.DialKeepAlive != 0 {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}
+		if conf.DialTimeout != 0 {
+			dialer.Timeout = conf.DialTimeout
+		}
+		if conf.DialKeepAlive != 0 {
+			dialer.KeepAlive = conf.DialKeepAlive
+		}
+		transport.DialContext = dialer.DialContext
 	}
+	return transport
+}
 
+// apply - iterates over all opts and runs the functions to apply additional request headers
+func (c *apiClient) apply(r *Request, opts []CallOption) (err error) {
 	for i := 0; i < len(opts); i++ {
 		// check to make sure someone did not pass nil ass a call option
 		if opts[i] != nil {
@@ -61,7 +136,31 @@ func (c *apiClient) apply(r *Request, opts []CallOption) (err error) {
 			}
 		}
 	}
-	return nil
+	r.ActivityIDHeaders()
+	return c.authorize(r)
+}
+
+// authorize - adds the default headers signed with a bearer token or master key
+func (c *apiClient) authorize(r *Request) error {
+	if tokens := c.tokenCache(); tokens != nil {
+		token, err := tokens.get(r.context())
+		if err != nil {
+			return err
+		}
+		r.TokenHeaders(token)
+		return nil
+	}
+	key, _, err := c.masterKeys()
+	if err != nil {
+		return err
+	}
+	return r.DefaultHeaders(key)
+}
+
+// tokenCache - returns the token cache when the client authorizes with a TokenCredential, it is set once by
+// newAPIClient
+func (c *apiClient) tokenCache() *tokenCache {
+	return c.tokens
 }
 
 // GetURI - returns a clients URI
@@ -71,19 +170,59 @@ func (c *apiClient) getURI() string {
 
 // GetConfig - return a clients URI
 func (c *apiClient) getConfig() Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.config
 }
 
 // EnableDebug - enables the CosmosDB debug mode
 func (c *apiClient) enableDebug() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.config.Debug = true
 }
 
 // DisableDebug - disables the CosmosDB debug mode
 func (c *apiClient) disableDebug() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.config.Debug = false
 }
 
+// setMasterKeys - swaps the master keys used to sign requests
+func (c *apiClient) setMasterKeys(primary, secondary string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config.MasterKey = primary
+	c.config.SecondaryKey = secondary
+	c.preferredKey = ""
+}
+
+// masterKeys - returns the key requests should be signed with first and the key to fall back to
+func (c *apiClient) masterKeys() (active, standby string, err error) {
+	c.mu.RLock()
+	provider := c.config.KeyProvider
+	primary, secondary := c.config.MasterKey, c.config.SecondaryKey
+	preferred := c.preferredKey
+	c.mu.RUnlock()
+	if provider != nil {
+		if primary, secondary, err = provider.MasterKeys(); err != nil {
+			return "", "", err
+		}
+	}
+	if secondary != "" && preferred == secondary {
+		return secondary, primary, nil
+	}
+	return primary, secondary, nil
+}
+
+// preferKey - signs future requests with the passed key first
+func (c *apiClient) preferKey(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.preferredKey = key
+}
+
 // Read - reads a resource by self link
 func (c *apiClient) read(link string, ret interface{}, opts ...CallOption) (*Response, error) {
 	return c.method("GET", link, http.StatusOK, ret, &bytes.Buffer{}, opts...)
@@ -120,7 +259,7 @@ func (c *apiClient) query(link, query string, ret interface{}, opts ...CallOptio
 
 // QueryWithParameters - queries a resource
 func (c *apiClient) queryWithParameters(link string, query *QueryWithParameters, ret interface{}, opts ...CallOption) (*Response, error) {
-	query.Query = escapeSQL(query.Query)
+	// the query is escaped by marshaling it, escaping it before would escape it twice
 	q, err := stringify(query)
 	if err != nil {
 		return nil, err
@@ -147,7 +286,7 @@ func (c *apiClient) queryWithParameters(link string, query *QueryWithParameters,
 
 // Create - creates a resource
 func (c *apiClient) create(link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
-	data, err := stringify(body)
+	data, err := c.marshal(body)
 	if err != nil {
 		return nil, err
 	}
@@ -157,7 +296,7 @@ func (c *apiClient) create(link string, body, ret interface{}, opts ...CallOptio
 
 // Replace - replaces a resource
 func (c *apiClient) replace(link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
-	data, err := stringify(body)
+	data, err := c.marshal(body)
 	if err != nil {
 		return nil, err
 	}
@@ -173,7 +312,7 @@ func (c *apiClient) replace(link string, body, ret interface{}, opts ...CallOpti
 // Upsert - upserts a resource
 func (c *apiClient) upsert(link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
 	opts = append(opts, Upsert())
-	data, err := stringify(body)
+	data, err := c.marshal(body)
 	if err != nil {
 		return nil, err
 	}
@@ -188,7 +327,7 @@ func (c *apiClient) upsert(link string, body, ret interface{}, opts ...CallOptio
 
 // ReplaceAsync - replaces a resource
 func (c *apiClient) replaceAsync(link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
-	data, err := stringify(body)
+	data, err := c.marshal(body)
 	if err != nil {
 		return nil, err
 	}
@@ -204,7 +343,7 @@ func (c *apiClient) replaceAsync(link string, body, ret interface{}, opts ...Cal
 			Etag = val
 		}
 	} else {
-		return nil, errors.New("_etag does not exist for async replace")
+		return nil, &ValidationError{"_etag does not exist for async replace"}
 	}
 	if c.config.PartitionKeyStructField != "" {
 		partKey := reflect.ValueOf(body).Elem().FieldByName(c.config.PartitionKeyStructField)
@@ -217,7 +356,7 @@ func (c *apiClient) replaceAsync(link string, body, ret interface{}, opts ...Cal
 
 // Execute - executes a resource
 func (c *apiClient) execute(link string, body, ret interface{}, opts ...CallOption) (*Response, error) {
-	data, err := stringify(body)
+	data, err := c.marshal(body)
 	if err != nil {
 		return nil, err
 	}
@@ -242,14 +381,86 @@ func (c *apiClient) method(method, link string, status int, ret interface{}, bod
 	return c.do(r, status, ret)
 }
 
-// do - private do function
-func (c *apiClient) do(r *Request, status int, data interface{}) (*Response, error) {
-	if c.config.Debug && c.logger != nil {
-		r.QueryMetricsHeaders()
-		c.logger.Infof("CosmosDB Request: ID: %+v, Type: %+v, HTTP Request: %+v", r.rId, r.rType, r.Request)
-		curl, _ := http2curl.GetCurlCommand(r.Request)
-		c.logger.Infof("CURL: %s", curl)
+// reauthorize - resends an unauthorized request signed with a fresh token or the standby master key
+func (c *apiClient) reauthorize(r *Request, rr *retryablehttp.Request, resp *http.Response) (*http.Response, error) {
+	var standby string
+	if tokens := c.tokenCache(); tokens != nil {
+		tokens.invalidate()
+		token, err := tokens.get(r.context())
+		if err != nil {
+			return resp, nil
+		}
+		r.TokenAuth(token)
+	} else {
+		var err error
+		if _, standby, err = c.masterKeys(); err != nil || standby == "" {
+			return resp, nil
+		}
+		if err = r.MasterKeyAuth(standby); err != nil {
+			return resp, nil
+		}
+	}
+	resp.Body.Close()
+	if d := diagnosticsFromContext(r.rContext); d != nil {
+		d.retrying(resp, nil)
+		d.retry()
+	}
+	resp, err := c.httpClient.Do(rr)
+	if err != nil {
+		return nil, &NetworkError{err}
+	}
+	if standby != "" && resp.StatusCode != http.StatusUnauthorized {
+		c.preferKey(standby)
+	}
+	return resp, nil
+}
+
+// timeout - returns the configured timeout for the kind of operation the request performs
+func (c *apiClient) timeout(r *Request) time.Duration {
+	feed := strings.HasSuffix(strings.TrimSuffix(r.URL.Path, "/"), "/"+r.rType)
+	switch {
+	case r.Header.Get(HeaderIsQuery) == "true":
+		return c.config.QueryTimeout
+	case r.rType == "docs" && feed && r.Method == http.MethodGet:
+		// reading a feed of documents pages like a query
+		return c.config.QueryTimeout
+	case r.rType == "docs" || r.rType == "attachments":
+		return c.config.PointTimeout
+	case r.rType == "sprocs" && !feed && r.Method == http.MethodPost:
+		// executing a stored procedure
+		return c.config.PointTimeout
+	default:
+		return c.config.MetadataTimeout
+	}
+}
+
+// send - sends the request through the circuit breaker, if one is configured
+func (c *apiClient) send(r *Request, rr *retryablehttp.Request) (*http.Response, error) {
+	if c.breaker == nil {
+		resp, err := c.httpClient.Do(rr)
+		if err != nil {
+			return nil, &NetworkError{err}
+		}
+		return resp, nil
+	}
+	key := circuitKeyFor(r)
+	if err := c.breaker.allow(key); err != nil {
+		return nil, err
 	}
+	resp, err := c.httpClient.Do(rr)
+	if err != nil && isCallerCanceled(r) {
+		c.breaker.release(key)
+	} else {
+		c.breaker.record(key, isCircuitFailure(r, resp, err))
+	}
+	if err != nil {
+		return nil, &NetworkError{err}
+	}
+	return resp, nil
+}
+
+// invoke - sends a request, the innermost invoker of the interceptor chain
+func (c *apiClient) invoke(r *Request) (*http.Response, error) {
 	var rr *retryablehttp.Request
 	var err error
 	if r.rContext != nil {
@@ -261,33 +472,62 @@ func (c *apiClient) do(r *Request, status int, data interface{}) (*Response, err
 	if err != nil {
 		return nil, fmt.Errorf("error creating retryable request: %s", err)
 	}
-	resp, err := c.httpClient.Do(rr)
+	if err = c.waitForRUs(r); err != nil {
+		return nil, err
+	}
+	resp, err := c.send(r, rr)
+	if err != nil {
+		return nil, err
+	}
+	// during a key rotation the key or token we signed with may have been revoked, try again
+	if resp.StatusCode == http.StatusUnauthorized {
+		if resp, err = c.reauthorize(r, rr, resp); err != nil {
+			return nil, err
+		}
+	}
+	c.chargeRUs(r, resp.Header)
+	return resp, nil
+}
+
+// do - private do function
+func (c *apiClient) do(r *Request, status int, data interface{}) (*Response, error) {
+	if timeout := c.timeout(r); timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.context(), timeout)
+		defer cancel()
+		r.rContext = ctx
+	}
+	diag := newDiagnostics(r)
+	resp, err := c.chain()(r)
 	if err != nil {
 		return nil, err
 	}
-	if c.config.Debug && c.config.Verbose && c.logger != nil {
-		c.logger.Infof("CosmosDB Request: %s", spew.Sdump(resp.Request))
-		c.logger.Infof("CosmosDB Response Headers: %s", spew.Sdump(resp.Header))
-		c.logger.Infof("CosmosDB Response Content-Length: %s", spew.Sdump(resp.ContentLength))
+	if resp == nil || resp.Body == nil {
+		// an interceptor short-circuited the request without a response
+		return nil, fmt.Errorf("no response for %s %s", r.Method, r.URL)
 	}
 	defer resp.Body.Close()
+	diag.response(resp)
+	var body io.Reader = resp.Body
+	if resp.ContentLength < 0 {
+		body = countingReader{resp.Body, &diag.ResponseSize}
+	}
 	if resp.StatusCode != status {
 		err := &RequestError{}
-		readJson(resp.Body, &err)
+		readJson(body, &err)
 		err.StatusCode = resp.StatusCode
 		err.RId = r.rId
 		err.RType = r.rType
 		err.Request = r.Request
+		err.SubStatus = diag.SubStatus
+		err.RetryAfter = retryAfter(resp.Header)
+		err.ActivityID = diag.ActivityID
+		err.RequestCharge = diag.RequestCharge
+		err.Diagnostics = diag.finish()
 		return nil, err
 	}
 	if data == nil {
-		return nil, nil
-	}
-	if c.config.Debug && c.config.Verbose && c.logger != nil {
-		c.logger.Infof("CosmosDB Request: %s", spew.Sdump(resp.Request))
-		c.logger.Infof("CosmosDB Response Headers: %s", spew.Sdump(resp.Header))
-		c.logger.Infof("CosmosDB Response Content-Length: %s", spew.Sdump(resp.ContentLength))
-		c.logger.Infof("CosmosDB Response Content: %s", spew.Sdump(data))
+		return &Response{Header: resp.Header, Diagnostics: diag.finish()}, nil
 	}
-	return &Response{resp.Header}, readJson(resp.Body, data)
+	err = c.decode(body, data)
+	return &Response{Header: resp.Header, Diagnostics: diag.finish()}, err
 }
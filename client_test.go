@@ -1,10 +1,13 @@
 package gocosmosdb
 
 import (
+	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"testing"
 	"time"
 
@@ -71,6 +74,28 @@ func ServerFactory(resp ...interface{}) *MockServer {
 	return s
 }
 
+// pagedServer - serves the pages in turn, the index of the next page is the continuation. A page that is a status
+// code is served as an error with that status.
+func pagedServer(pages ...string) (*httptest.Server, *[]*http.Request) {
+	requests := []*http.Request{}
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		i := 0
+		fmt.Sscan(r.Header.Get(HeaderContinuation), &i)
+		w.Header().Set(HeaderRequestCharge, "2.5")
+		if status, err := strconv.Atoi(pages[i]); err == nil {
+			http.Error(w, `{"code": "Error", "message": "page failed"}`, status)
+			return
+		}
+		if i+1 < len(pages) {
+			w.Header().Set(HeaderContinuation, fmt.Sprint(i+1))
+		}
+		w.Header().Set(HeaderSessionToken, "0:1")
+		fmt.Fprint(w, pages[i])
+	}))
+	return s, &requests
+}
+
 func TestGetURI(t *testing.T) {
 	assert := assert.New(t)
 	client := &apiClient{
@@ -318,3 +343,164 @@ func TestExecute(t *testing.T) {
 	_, err = client.execute("dbs", tDoc, &doc)
 	assert.Contains(err.Error(), "giving up after 1 attempts")
 }
+
+func TestReadFallsBackToSecondaryKey(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(401, `{"_colls": "colls"}`, `{"_colls": "colls"}`)
+	defer s.Close()
+	client := &apiClient{
+		uri: s.URL,
+		config: Config{
+			MasterKey:    "YXJpZWwNCg==",
+			SecondaryKey: "c2Vjb25kYXJ5DQo=",
+		},
+		httpClient: httpClient,
+		logger:     log,
+	}
+
+	// First call is unauthorized with the primary key and retried with the secondary key
+	var db Database
+	_, err := client.read("dbs/b7NTAS==/", &db)
+	assert.Nil(err, "err should be nil")
+	assert.Equal(db.Colls, "colls", "Should fill the fields from response body")
+	assert.Equal("c2Vjb25kYXJ5DQo=", client.preferredKey)
+	// the retried request is signed with the secondary key
+	sign := func(key string) string {
+		req, _ := http.NewRequest("GET", s.URL, nil)
+		r := ResourceRequest("dbs/b7NTAS==/", req)
+		r.Header.Set(HeaderXDate, s.Header.Get(HeaderXDate))
+		r.Header.Set("Date", s.Header.Get("Date"))
+		assert.Nil(r.MasterKeyAuth(key))
+		return r.Header.Get(HeaderAuth)
+	}
+	assert.Equal(sign("c2Vjb25kYXJ5DQo="), s.Header.Get(HeaderAuth))
+	assert.NotEqual(sign("YXJpZWwNCg=="), s.Header.Get(HeaderAuth))
+
+	// Second call is signed with the secondary key first
+	_, err = client.read("dbs/b7NTAS==/", &db)
+	assert.Nil(err, "err should be nil")
+	active, standby, err := client.masterKeys()
+	assert.Nil(err)
+	assert.Equal("c2Vjb25kYXJ5DQo=", active)
+	assert.Equal("YXJpZWwNCg==", standby)
+	assert.Equal(sign("c2Vjb25kYXJ5DQo="), s.Header.Get(HeaderAuth))
+}
+
+func TestReadUnauthorizedWithoutSecondaryKey(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(401)
+	defer s.Close()
+	client := &apiClient{
+		uri: s.URL,
+		config: Config{
+			MasterKey: "YXJpZWwNCg==",
+		},
+		httpClient: httpClient,
+		logger:     log,
+	}
+
+	var db Database
+	_, err := client.read("dbs/b7NTAS==/", &db)
+	assert.NotNil(err)
+	assert.Equal(http.StatusUnauthorized, err.(*RequestError).StatusCode)
+}
+
+type countingTransport struct {
+	calls int
+}
+
+func (t *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	t.calls++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestInjectedHTTPClient(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(`{"_colls": "colls"}`, `{"_colls": "colls"}`)
+	defer s.Close()
+
+	// a supplied transport is used by the default client
+	transport := &countingTransport{}
+	client := newAPIClient(&Config{MasterKey: "YXJpZWwNCg==", Transport: transport})
+	client.uri = s.URL
+	var db Database
+	_, err := client.read("dbs/b7NTAS==/", &db)
+	assert.Nil(err)
+	assert.Equal(1, transport.calls)
+
+	// a supplied client replaces the default client
+	transport = &countingTransport{}
+	client = newAPIClient(&Config{MasterKey: "YXJpZWwNCg==", HTTPClient: &http.Client{Transport: transport}})
+	client.uri = s.URL
+	_, err = client.read("dbs/b7NTAS==/", &db)
+	assert.Nil(err)
+	assert.Equal(1, transport.calls)
+}
+
+func TestNewTransport(t *testing.T) {
+	assert := assert.New(t)
+	assert.Nil(newTransport(&Config{}))
+
+	proxyURL, _ := url.Parse("http://proxy:3128")
+	conf := &Config{
+		Proxy:         http.ProxyURL(proxyURL),
+		TLSConfig:     &tls.Config{ServerName: "localhost"},
+		DialTimeout:   time.Second,
+		DialKeepAlive: time.Minute,
+	}
+	transport, ok := newTransport(conf).(*http.Transport)
+	assert.True(ok)
+	req, _ := http.NewRequest("GET", "https://localhost:8081/", nil)
+	proxy, err := transport.Proxy(req)
+	assert.Nil(err)
+	assert.Equal(proxyURL, proxy)
+	assert.Equal("localhost", transport.TLSClientConfig.ServerName)
+	assert.NotNil(transport.DialContext)
+}
+
+func TestOperationTimeouts(t *testing.T) {
+	assert := assert.New(t)
+	client := &apiClient{
+		config: Config{
+			PointTimeout:    1 * time.Second,
+			QueryTimeout:    2 * time.Second,
+			MetadataTimeout: 3 * time.Second,
+		},
+	}
+	request := func(method, link string) *Request {
+		req, _ := http.NewRequest(method, path("https://localhost:8081", link), nil)
+		return ResourceRequest(link, req)
+	}
+
+	assert.Equal(1*time.Second, client.timeout(request("GET", "dbs/mydb/colls/mycoll/docs/mydoc")))
+	assert.Equal(1*time.Second, client.timeout(request("POST", "dbs/mydb/colls/mycoll/docs/")))
+	assert.Equal(1*time.Second, client.timeout(request("POST", "dbs/mydb/colls/mycoll/sprocs/mysproc")))
+	assert.Equal(2*time.Second, client.timeout(request("GET", "dbs/mydb/colls/mycoll/docs/")))
+	assert.Equal(2*time.Second, client.timeout(request("GET", "dbs/d9RzAA==/colls/d9RzAJRFKgw=/docs/")))
+	query := request("POST", "dbs/mydb/colls/")
+	query.QueryHeaders(0)
+	assert.Equal(2*time.Second, client.timeout(query))
+	assert.Equal(3*time.Second, client.timeout(request("GET", "dbs/mydb/colls/mycoll")))
+	assert.Equal(3*time.Second, client.timeout(request("GET", "dbs/")))
+}
+
+func TestPointTimeout(t *testing.T) {
+	assert := assert.New(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		fmt.Fprintln(w, `{"id": "9"}`)
+	}))
+	defer s.Close()
+	client := newAPIClient(&Config{MasterKey: "YXJpZWwNCg==", PointTimeout: 10 * time.Millisecond})
+	client.uri = s.URL
+
+	var doc Document
+	_, err := client.read("dbs/mydb/colls/mycoll/docs/9", &doc)
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "context deadline exceeded")
+
+	// metadata operations are not bound by the point timeout
+	var db Database
+	_, err = client.read("dbs/mydb", &db)
+	assert.Nil(err)
+}
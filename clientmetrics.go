@@ -0,0 +1,216 @@
+package gocosmosdb
+
+import (
+	"bytes"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets - the default upper bounds, in seconds, of the request latency histogram
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// ClientMetrics - records request counts, latency, request units, retries and throttles, labeled by
+// operation, resource type, collection and status. Set it as Config.Metrics and expose it with Handler or Publish.
+type ClientMetrics struct {
+	buckets []float64
+	mu      sync.Mutex
+	series  map[MetricLabels]*metricSeries
+}
+
+// MetricLabels - the labels of a series of metrics
+type MetricLabels struct {
+	Operation    string `json:"operation"`
+	ResourceType string `json:"resourceType"`
+	Collection   string `json:"collection"`
+	Status       string `json:"status"`
+}
+
+// MetricSnapshot - the values of a series of metrics at a point in time
+type MetricSnapshot struct {
+	MetricLabels
+	Requests       uint64    `json:"requests"`
+	Retries        uint64    `json:"retries"`
+	Throttles      uint64    `json:"throttles"`
+	RequestCharge  float64   `json:"requestCharge"`
+	LatencySeconds float64   `json:"latencySeconds"`
+	LatencyBuckets []float64 `json:"latencyBuckets"`
+	LatencyCounts  []uint64  `json:"latencyCounts"`
+}
+
+type metricSeries struct {
+	requests  uint64
+	retries   uint64
+	throttles uint64
+	charge    float64
+	latency   float64
+	counts    []uint64
+}
+
+// NewClientMetrics - creates a metrics collector, with DefaultLatencyBuckets when no buckets are passed
+func NewClientMetrics(buckets ...float64) *ClientMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return &ClientMetrics{buckets: buckets, series: map[MetricLabels]*metricSeries{}}
+}
+
+// Interceptor - returns an interceptor recording the metrics of every request
+func (m *ClientMetrics) Interceptor() Interceptor {
+	return func(r *Request, next Invoker) (*http.Response, error) {
+		start := time.Now()
+		resp, err := next(r)
+		labels := MetricLabels{
+			Operation:    r.Operation(),
+			ResourceType: r.ResourceType(),
+			Collection:   r.Collection(),
+			Status:       "error",
+		}
+		var charge float64
+		if resp != nil {
+			labels.Status = strconv.Itoa(resp.StatusCode)
+			charge, _ = strconv.ParseFloat(resp.Header.Get(HeaderRequestCharge), 64)
+		}
+		var retries int
+		if d := diagnosticsFromContext(r.rContext); d != nil {
+			retries = d.Retries
+		}
+		m.record(labels, time.Since(start), charge, retries)
+		return resp, err
+	}
+}
+
+// record - adds a request to the series of its labels
+func (m *ClientMetrics) record(labels MetricLabels, latency time.Duration, charge float64, retries int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.series[labels]
+	if !ok {
+		s = &metricSeries{counts: make([]uint64, len(m.buckets))}
+		m.series[labels] = s
+	}
+	s.requests++
+	s.retries += uint64(retries)
+	if labels.Status == strconv.Itoa(http.StatusTooManyRequests) {
+		s.throttles++
+	}
+	s.charge += charge
+	seconds := latency.Seconds()
+	s.latency += seconds
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			s.counts[i]++
+		}
+	}
+}
+
+// Snapshot - returns the current values of every series, sorted by labels
+func (m *ClientMetrics) Snapshot() []MetricSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make([]MetricSnapshot, 0, len(m.series))
+	for labels, s := range m.series {
+		snapshot = append(snapshot, MetricSnapshot{
+			MetricLabels:   labels,
+			Requests:       s.requests,
+			Retries:        s.retries,
+			Throttles:      s.throttles,
+			RequestCharge:  s.charge,
+			LatencySeconds: s.latency,
+			LatencyBuckets: m.buckets,
+			LatencyCounts:  append([]uint64{}, s.counts...),
+		})
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		a, b := snapshot[i].MetricLabels, snapshot[j].MetricLabels
+		if a.Operation != b.Operation {
+			return a.Operation < b.Operation
+		}
+		if a.ResourceType != b.ResourceType {
+			return a.ResourceType < b.ResourceType
+		}
+		if a.Collection != b.Collection {
+			return a.Collection < b.Collection
+		}
+		return a.Status < b.Status
+	})
+	return snapshot
+}
+
+// WriteText - writes the metrics in the Prometheus / OpenMetrics text exposition format
+func (m *ClientMetrics) WriteText(w io.Writer) error {
+	snapshot := m.Snapshot()
+	var buf bytes.Buffer
+	counter := func(name, help string, value func(s MetricSnapshot) string) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+		for _, s := range snapshot {
+			fmt.Fprintf(&buf, "%s{%s} %s\n", name, s.labels(), value(s))
+		}
+	}
+	counter("gocosmosdb_requests_total", "Requests sent to Cosmos DB.", func(s MetricSnapshot) string {
+		return strconv.FormatUint(s.Requests, 10)
+	})
+	counter("gocosmosdb_request_charge_total", "Request units consumed.", func(s MetricSnapshot) string {
+		return formatFloat(s.RequestCharge)
+	})
+	counter("gocosmosdb_retries_total", "Requests retried after a failed attempt.", func(s MetricSnapshot) string {
+		return strconv.FormatUint(s.Retries, 10)
+	})
+	counter("gocosmosdb_throttles_total", "Requests throttled with a 429 status.", func(s MetricSnapshot) string {
+		return strconv.FormatUint(s.Throttles, 10)
+	})
+	name := "gocosmosdb_request_duration_seconds"
+	fmt.Fprintf(&buf, "# HELP %s Request latency in seconds.\n# TYPE %s histogram\n", name, name)
+	for _, s := range snapshot {
+		labels := s.labels()
+		for i, bound := range s.LatencyBuckets {
+			fmt.Fprintf(&buf, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(bound), s.LatencyCounts[i])
+		}
+		fmt.Fprintf(&buf, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, s.Requests)
+		fmt.Fprintf(&buf, "%s_sum{%s} %s\n", name, labels, formatFloat(s.LatencySeconds))
+		fmt.Fprintf(&buf, "%s_count{%s} %d\n", name, labels, s.Requests)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Handler - returns an http.Handler serving the metrics in the Prometheus text format
+func (m *ClientMetrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		m.WriteText(w)
+	})
+}
+
+// Publish - exposes the metrics through expvar under name, it panics if name is already published
+func (m *ClientMetrics) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return m.Snapshot()
+	}))
+}
+
+// labels - formats the labels of a snapshot for the text format
+func (s MetricSnapshot) labels() string {
+	return fmt.Sprintf(`operation="%s",resource_type="%s",collection="%s",status="%s"`,
+		escapeLabel(s.Operation), escapeLabel(s.ResourceType), escapeLabel(s.Collection), escapeLabel(s.Status))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel - escapes a label value for the text format
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+// formatFloat - formats a float for the text format
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
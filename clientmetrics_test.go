@@ -0,0 +1,60 @@
+package gocosmosdb
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientMetrics(t *testing.T) {
+	assert := assert.New(t)
+	s := chargingServer("2.5")
+	defer s.Close()
+	metrics := NewClientMetrics(0.5, 10)
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", Metrics: metrics}, log)
+	docs := []Document{}
+	for i := 0; i < 2; i++ {
+		_, err := client.QueryDocuments("dbs/db/colls/coll/", "SELECT * FROM c", &docs)
+		assert.Nil(err)
+	}
+	_, err := client.ReadDocument("dbs/db/colls/coll/docs/1", &Document{})
+	assert.Nil(err)
+
+	snapshot := metrics.Snapshot()
+	assert.Len(snapshot, 2)
+	assert.Equal(MetricLabels{"QueryDocuments", "docs", "coll", "200"}, snapshot[0].MetricLabels)
+	assert.Equal(uint64(2), snapshot[0].Requests)
+	assert.Equal(5.0, snapshot[0].RequestCharge)
+	assert.Equal([]uint64{2, 2}, snapshot[0].LatencyCounts)
+	assert.Equal("ReadDocument", snapshot[1].Operation)
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	labels := `operation="QueryDocuments",resource_type="docs",collection="coll",status="200"`
+	assert.Contains(body, "# TYPE gocosmosdb_requests_total counter\n")
+	assert.Contains(body, "gocosmosdb_requests_total{"+labels+"} 2\n")
+	assert.Contains(body, "gocosmosdb_request_charge_total{"+labels+"} 5\n")
+	assert.Contains(body, "gocosmosdb_request_duration_seconds_bucket{"+labels+`,le="0.5"} 2`+"\n")
+	assert.Contains(body, "gocosmosdb_request_duration_seconds_bucket{"+labels+`,le="+Inf"} 2`+"\n")
+	assert.Contains(body, "gocosmosdb_request_duration_seconds_count{"+labels+"} 2\n")
+}
+
+func TestClientMetricsThrottlesAndRetries(t *testing.T) {
+	assert := assert.New(t)
+	metrics := NewClientMetrics()
+	metrics.record(MetricLabels{"ReadDocument", "docs", "coll", "429"}, 0, 1, 3)
+	metrics.record(MetricLabels{"ReadDocument", "docs", "coll", "error"}, 0, 0, 0)
+	snapshot := metrics.Snapshot()
+	assert.Equal(uint64(1), snapshot[0].Throttles)
+	assert.Equal(uint64(3), snapshot[0].Retries)
+	assert.Equal(uint64(0), snapshot[1].Throttles)
+
+	var buf bytes.Buffer
+	assert.Nil(metrics.WriteText(&buf))
+	assert.Contains(buf.String(), `gocosmosdb_throttles_total{operation="ReadDocument",resource_type="docs",collection="coll",status="429"} 1`)
+	assert.Equal(`a\"b\\c\n`, escapeLabel("a\"b\\c\n"))
+}
@@ -0,0 +1,70 @@
+package gocosmosdb
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+)
+
+// Codec - encodes document bodies and decodes response bodies
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Decode(r io.Reader, v interface{}) error
+}
+
+// JSONCodec - the default Codec backed by encoding/json
+type JSONCodec struct{}
+
+// Marshal - encodes v with json.Marshal
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Decode - decodes the next JSON value of r into v
+func (JSONCodec) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// codec - returns the configured codec or the default JSONCodec
+func (c *apiClient) codec() Codec {
+	if c.config.Codec != nil {
+		return c.config.Codec
+	}
+	return JSONCodec{}
+}
+
+// marshal - encodes a request body, strings and bytes are sent as is
+func (c *apiClient) marshal(body interface{}) ([]byte, error) {
+	switch body.(type) {
+	case string, []byte, json.RawMessage:
+		return stringify(body)
+	}
+	return c.codec().Marshal(body)
+}
+
+// decode - decodes a response body into data, raw messages are filled without a decode step
+// and body handlers read the body themselves
+func (c *apiClient) decode(reader io.Reader, data interface{}) (err error) {
+	if handler, ok := data.(bodyHandler); ok {
+		return handler(reader)
+	}
+	if raw := rawMessage(data); raw != nil {
+		*raw, err = ioutil.ReadAll(reader)
+		return err
+	}
+	return c.codec().Decode(reader, data)
+}
+
+// rawMessage - returns the *json.RawMessage data points to, if any
+func rawMessage(data interface{}) *json.RawMessage {
+	for {
+		switch v := data.(type) {
+		case *json.RawMessage:
+			return v
+		case *interface{}:
+			data = *v
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package gocosmosdb
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type upperCodec struct {
+	JSONCodec
+	marshaled int
+	decoded   int
+}
+
+func (c *upperCodec) Marshal(v interface{}) ([]byte, error) {
+	c.marshaled++
+	b, err := c.JSONCodec.Marshal(v)
+	return []byte(strings.ToUpper(string(b))), err
+}
+
+func (c *upperCodec) Decode(r io.Reader, v interface{}) error {
+	c.decoded++
+	return c.JSONCodec.Decode(r, v)
+}
+
+func TestJSONCodecCompatible(t *testing.T) {
+	assert := assert.New(t)
+	doc := testDoc{PONumber: "PO18009186470 <&>"}
+	doc.Id = "SalesOrder1"
+	client := &apiClient{}
+	b, err := client.marshal(&doc)
+	assert.Nil(err)
+	expected, _ := json.Marshal(&doc)
+	assert.Equal(expected, b)
+
+	b, err = client.marshal(json.RawMessage(`{ "id": "raw" }`))
+	assert.Nil(err)
+	assert.Equal(`{ "id": "raw" }`, string(b))
+}
+
+func TestCustomCodec(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(`{"id": "9"}`)
+	s.SetStatus(201)
+	defer s.Close()
+	codec := &upperCodec{}
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", Codec: codec}, log)
+	doc := testDoc{PONumber: "po1"}
+	doc.Id = "9"
+	_, err := client.CreateDocument("dbs/d9RzAA==/colls/d9RzAJRFKgw=", &doc)
+	assert.Nil(err)
+	assert.Equal(1, codec.marshaled)
+	assert.Equal(1, codec.decoded)
+	assert.Contains(s.Body, `"PONUMBER":"PO1"`)
+}
+
+func TestReadDocumentRaw(t *testing.T) {
+	assert := assert.New(t)
+	resp := `{"id": "SalesOrder1", "ponumber": "PO18009186470"}`
+	s := ServerFactory(resp)
+	defer s.Close()
+	codec := &upperCodec{}
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", Codec: codec}, log)
+	var raw json.RawMessage
+	_, err := client.ReadDocument("dbs/d9RzAA==/colls/d9RzAJRFKgw=/docs/d9RzAJRFKgwBAAAAAAAAAA==", &raw)
+	assert.Nil(err)
+	assert.Equal(resp+"\n", string(raw))
+	assert.Equal(0, codec.decoded)
+}
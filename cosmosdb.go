@@ -5,16 +5,20 @@
 package gocosmosdb
 
 import (
-	"errors"
+	"crypto/tls"
+	"net/http"
+	"net/url"
 	"reflect"
 	"time"
-
-	"github.com/intwinelabs/logger"
 )
 
 // Config - Stores configuration for the gocosmosdb client
 type Config struct {
 	MasterKey               string
+	SecondaryKey            string          // used when a request signed with MasterKey is unauthorized
+	KeyProvider             KeyProvider     // when set, keys are loaded from the provider instead of MasterKey and SecondaryKey
+	TokenCredential         TokenCredential // when set, requests are authorized with AAD bearer tokens instead of master keys
+	TokenRefreshBefore      time.Duration   // how long before expiry a token is refreshed, defaults to 5 minutes
 	Debug                   bool
 	Verbose                 bool
 	PartitionKeyStructField string // eg. "Id"
@@ -23,17 +27,37 @@ type Config struct {
 	RetryWaitMax            time.Duration
 	RetryMax                int
 	Pooled                  bool
+	HTTPClient              *http.Client                          // when set, used to send requests instead of the default client
+	Transport               http.RoundTripper                     // when set, used by the default client instead of a transport built from the settings below
+	Proxy                   func(*http.Request) (*url.URL, error) // eg. http.ProxyURL(proxyURL)
+	TLSConfig               *tls.Config                           // eg. RootCAs trusting the emulator's self-signed certificate
+	DialTimeout             time.Duration
+	DialKeepAlive           time.Duration
+	PointTimeout            time.Duration         // timeout for document reads and writes
+	QueryTimeout            time.Duration         // timeout for each page of a query or feed
+	MetadataTimeout         time.Duration         // timeout for database, collection, stored procedure and user defined function operations
+	Codec                   Codec                 // encodes documents and decodes responses, defaults to JSONCodec
+	RULimit                 float64               // request units per second this client may consume, 0 disables the limiter
+	RUBurst                 float64               // request units the limiter allows in a burst, defaults to RULimit
+	CircuitBreaker          *CircuitBreakerConfig // when set, failing endpoints and partition key ranges are short-circuited
+	Interceptors            []Interceptor         // run in order around every request
+	Metrics                 *ClientMetrics        // when set, records metrics for every request
+	Tracer                  Tracer                // when set, traces every operation and HTTP attempt
+	RedactFields            []string              // document fields replaced with Redacted in debug output
+	Recorder                Recorder              // when set, captures every request and response eg. to a HAR file
+	CursorKey               []byte                // when set, pagable query cursors are signed and only signed cursors are accepted
+	SlowQueryLog            *SlowQueryLog         // when set, query metrics are collected and queries over its thresholds are logged
 }
 
 // CosmosDB - Struct that stores the client and logger
 type CosmosDB struct {
 	client *apiClient
 	Config Config
-	Logger *logger.Logger
+	Logger Logger
 }
 
 // New - Creates CosmosDB Client and returns it
-func New(url string, config Config, log *logger.Logger) *CosmosDB {
+func New(url string, config Config, log Logger) *CosmosDB {
 	client := newAPIClient(&config)
 	client.uri = url
 	client.config = config
@@ -61,6 +85,25 @@ func (c *CosmosDB) DisableDebug() {
 	c.client.disableDebug()
 }
 
+// SetMasterKeys - swaps the primary and secondary master keys used to sign requests, safe for concurrent use. The
+// Config field keeps the keys the client was created with, GetConfig returns the keys in use.
+func (c *CosmosDB) SetMasterKeys(primary, secondary string) {
+	c.client.setMasterKeys(primary, secondary)
+}
+
+// Use - appends interceptors to the chain run around every request
+func (c *CosmosDB) Use(interceptors ...Interceptor) {
+	c.client.use(interceptors...)
+}
+
+// CircuitBreakerStatus - returns the state of every circuit that has recorded failures
+func (c *CosmosDB) CircuitBreakerStatus() []CircuitStatus {
+	if c.client.breaker == nil {
+		return nil
+	}
+	return c.client.breaker.status()
+}
+
 // ReadDatabase - Retrieves a database resource by performing a GET on the database resource.
 //	db, err := client.ReadDatabase("dbs/{db-id}")
 func (c *CosmosDB) ReadDatabase(link string, opts ...CallOption) (db *Database, err error) {
@@ -108,37 +151,37 @@ func (c *CosmosDB) ReadUserDefinedFunction(link string, opts ...CallOption) (udf
 	return
 }
 
-// ReadDatabases - Retrieves all databases by performing a GET on a specific account.
+// ReadDatabases - Retrieves the first page of databases by performing a GET on a specific account, use ReadAllDatabases to read every page.
 //	dbs, err := client.ReadDatabases("dbs")
 func (c *CosmosDB) ReadDatabases(opts ...CallOption) (dbs []Database, err error) {
 	return c.QueryDatabases("", opts...)
 }
 
-// ReadCollections - Retrieves all collections by performing a GET on a specific database.
+// ReadCollections - Retrieves the first page of collections by performing a GET on a specific database, use ReadAllCollections to read every page.
 //	colls, err := client.ReadCollections("dbs/{db-id}/colls")
 func (c *CosmosDB) ReadCollections(db string, opts ...CallOption) (colls []Collection, err error) {
 	return c.QueryCollections(db, "", opts...)
 }
 
-// ReadStoredProcedures - Retrieves all stored procedures by performing a GET on a specific database.
+// ReadStoredProcedures - Retrieves the first page of stored procedures by performing a GET on a specific collection, use ReadAllStoredProcedures to read every page.
 //	sprocs, err := client.ReadStoredProcedures("dbs/{db-id}/sprocs")
 func (c *CosmosDB) ReadStoredProcedures(coll string, opts ...CallOption) (sprocs []Sproc, err error) {
 	return c.QueryStoredProcedures(coll, "", opts...)
 }
 
-// ReadUserDefinedFunctions - Retrieves all user defined functions by performing a GET on a specific database.
+// ReadUserDefinedFunctions - Retrieves the first page of user defined functions by performing a GET on a specific collection, use ReadAllUserDefinedFunctions to read every page.
 //	udfs, err := client.ReadUserDefinedFunctions("dbs/{db-id}/udfs")
 func (c *CosmosDB) ReadUserDefinedFunctions(coll string, opts ...CallOption) (udfs []UDF, err error) {
 	return c.QueryUserDefinedFunctions(coll, "", opts...)
 }
 
-// ReadDocuments - Retrieves a stored procedure by performing a GET on a specific stored procedure resource.
+// ReadDocuments - Retrieves the first page of documents by performing a GET on a specific collection, pass the Continuation of the response to read the next page or use ReadAllDocuments to read every page.
 //	err = client.ReadDocuments("dbs/{db-id}/colls/{coll-id}/docs", &docStructSlice)
 func (c *CosmosDB) ReadDocuments(coll string, docs interface{}, opts ...CallOption) (*Response, error) {
 	return c.QueryDocuments(coll, "", docs, opts...)
 }
 
-// QueryDatabases - Retrieves all databases that satisfy the passed query.
+// QueryDatabases - Retrieves the first page of databases that satisfy the passed query, use QueryAllDatabases to read every page.
 //	dbs, err := client.QueryDatabases("SELECT * FROM ROOT r")
 func (c *CosmosDB) QueryDatabases(query string, opts ...CallOption) (dbs []Database, err error) {
 	data := struct {
@@ -156,7 +199,7 @@ func (c *CosmosDB) QueryDatabases(query string, opts ...CallOption) (dbs []Datab
 	return
 }
 
-// QueryCollections - Retrieves all collections that satisfy passed query.
+// QueryCollections - Retrieves the first page of collections that satisfy passed query, use QueryAllCollections to read every page.
 //	colls, err := client.QueryCollections("SELECT * FROM ROOT r")
 func (c *CosmosDB) QueryCollections(db, query string, opts ...CallOption) (colls []Collection, err error) {
 	data := struct {
@@ -174,7 +217,7 @@ func (c *CosmosDB) QueryCollections(db, query string, opts ...CallOption) (colls
 	return
 }
 
-// QueryStoredProcedures - Retrieves all stored procedures that satisfy the passed query.
+// QueryStoredProcedures - Retrieves the first page of stored procedures that satisfy the passed query, use QueryAllStoredProcedures to read every page.
 //	colls, err := client.QueryStoredProcedures("SELECT * FROM ROOT r")
 func (c *CosmosDB) QueryStoredProcedures(coll, query string, opts ...CallOption) (sprocs []Sproc, err error) {
 	data := struct {
@@ -192,7 +235,7 @@ func (c *CosmosDB) QueryStoredProcedures(coll, query string, opts ...CallOption)
 	return
 }
 
-// QueryUserDefinedFunctions - Retrieves all user defined functions that satisfy the passed query.
+// QueryUserDefinedFunctions - Retrieves the first page of user defined functions that satisfy the passed query, use QueryAllUserDefinedFunctions to read every page.
 //	colls, err := client.QueryUserDefinedFunctions("SELECT * FROM ROOT r")
 func (c *CosmosDB) QueryUserDefinedFunctions(coll, query string, opts ...CallOption) (udfs []UDF, err error) {
 	data := struct {
@@ -210,7 +253,10 @@ func (c *CosmosDB) QueryUserDefinedFunctions(coll, query string, opts ...CallOpt
 	return
 }
 
-// QueryDocuments - Retrieves all documents in a collection that satisfy the passed query and marshals them into the passed interface.
+// QueryDocuments - Retrieves the first page of documents in a collection that satisfy the passed query and marshals them into the passed interface.
+// Pass the Continuation of the response to read the next page or use QueryAllDocuments to read every page.
+// Across partitions the service orders and aggregates the results of each partition key range only, use
+// NewCrossPartitionQuery for ORDER BY, aggregate, GROUP BY and DISTINCT queries spanning partitions.
 //	err := client.QueryDocuments(coll, "SELECT * FROM ROOT r", &docs)
 func (c *CosmosDB) QueryDocuments(coll, query string, docs interface{}, opts ...CallOption) (resp *Response, err error) {
 	data := struct {
@@ -220,12 +266,15 @@ func (c *CosmosDB) QueryDocuments(coll, query string, docs interface{}, opts ...
 	if len(query) > 0 {
 		resp, err = c.client.query(coll+"docs/", query, &data, opts...)
 	} else {
-		resp, err = c.client.read(coll+"docs/", &data)
+		resp, err = c.client.read(coll+"docs/", &data, opts...)
 	}
 	return
 }
 
-// QueryDocumentsWithParameters - Retrieves all documents in a collection that satisfy a passed query with parameters and marshals them into the passed interface.
+// QueryDocumentsWithParameters - Retrieves the first page of documents in a collection that satisfy a passed query with parameters and marshals them into the passed interface.
+// Pass the Continuation of the response to read the next page or use QueryAllDocumentsWithParameters to read every page.
+// Across partitions the service orders and aggregates the results of each partition key range only, use
+// NewCrossPartitionQuery for ORDER BY, aggregate, GROUP BY and DISTINCT queries spanning partitions.
 //	err := client.QueryDocumentsWithParameters(coll, queryWithParams, &docs)
 func (c *CosmosDB) QueryDocumentsWithParameters(coll string, query *QueryWithParameters, docs interface{}, opts ...CallOption) (resp *Response, err error) {
 	data := struct {
@@ -235,12 +284,12 @@ func (c *CosmosDB) QueryDocumentsWithParameters(coll string, query *QueryWithPar
 	if query != nil {
 		resp, err = c.client.queryWithParameters(coll+"docs/", query, &data, opts...)
 	} else {
-		err = errors.New("QueryWithParameters cannot be nil")
+		err = &ValidationError{"QueryWithParameters cannot be nil"}
 	}
 	return
 }
 
-// QueryPartitionKeyRanges - Retrieves all partition ranges in a collection.
+// QueryPartitionKeyRanges - Retrieves the first page of partition ranges in a collection, use QueryAllPartitionKeyRanges to read every page.
 //	pks, err := client.QueryPartitionKeyRanges(coll, "SELECT * FROM ROOT r")
 func (c *CosmosDB) QueryPartitionKeyRanges(coll string, query string, opts ...CallOption) (ranges []PartitionKeyRange, err error) {
 	data := struct {
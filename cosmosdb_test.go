@@ -27,6 +27,15 @@ func TestNew(t *testing.T) {
 	assert.Equal(false, conf.Debug)
 }
 
+func TestSetMasterKeys(t *testing.T) {
+	assert := assert.New(t)
+	client := New("url", Config{MasterKey: "config"}, log)
+	client.SetMasterKeys("primary", "secondary")
+	conf := client.GetConfig()
+	assert.Equal("primary", conf.MasterKey)
+	assert.Equal("secondary", conf.SecondaryKey)
+}
+
 func TestNewRetryable(t *testing.T) {
 	assert := assert.New(t)
 	c := Config{
@@ -1215,7 +1224,8 @@ func TestExecuteStoredProcedureWithContextTimeout(t *testing.T) {
 	s := ServerFactory(500, 500, 500, 500)
 	defer s.Close()
 	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", RetryWaitMin: 100 * time.Millisecond, RetryWaitMax: 100 * time.Millisecond, RetryMax: 3}, log)
-	ctx, _ := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
 	docs := []testDoc{}
 	_, err := client.ExecuteStoredProcedure("dbs/Sl8fAA==/colls/Sl8fALN4sw4=/sprocs/Sl8fALN4sw4CAAAAAAAAgA==", []string{"param1"}, &docs, WithContext(ctx))
 	assert.NotNil(err)
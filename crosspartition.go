@@ -0,0 +1,595 @@
+package gocosmosdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// CrossPartitionQuery - a query executed across every partition key range of a collection, results are merged on
+// the client following the query plan of the service so ORDER BY queries are ordered across partitions and
+// aggregates, GROUP BY, DISTINCT, TOP and OFFSET LIMIT queries return the same results as on a single partition.
+// The continuation token of a DISTINCT query without ORDER BY holds every result returned so far, Continuation
+// returns an error once there are more than 1000 of them.
+//	query := client.NewCrossPartitionQuery(coll, gocosmosdb.Select().OrderBy("c.name").Build(), 100)
+//	for !query.Done() {
+//		docs := []Document{}
+//		if err := query.Next(&docs); err != nil {
+//			return err
+//		}
+//	}
+// The partition key ranges are read one after the other unless SetMaxDegreeOfParallelism is set. Every request of
+// the query carries the same activity ID, the one set with ActivityID or WithActivityID or a new one.
+type CrossPartitionQuery struct {
+	client       *CosmosDB
+	coll         string
+	query        *QueryWithParameters
+	pageSize     int
+	opts         []CallOption
+	continuation string
+	activityID   string
+	plan         *queryPlan
+	source       querySource
+	parallelism  int
+	prefetch     int
+	ctx          context.Context
+	cancel       context.CancelFunc
+	mu           sync.Mutex
+	charge       float64
+	done         bool
+}
+
+// NewCrossPartitionQuery - creates a query returning at most pageSize results per page, all results when it is 0
+func (c *CosmosDB) NewCrossPartitionQuery(coll string, query *QueryWithParameters, pageSize int, opts ...CallOption) *CrossPartitionQuery {
+	return &CrossPartitionQuery{
+		client:   c,
+		coll:     coll,
+		query:    query,
+		pageSize: pageSize,
+		opts:     opts,
+	}
+}
+
+// SetContinuation - resumes the query after the page a continuation token was returned with. Next returns a
+// RangeGoneError, matched by IsPartitionGone, when a partition key range the token still reads has been split since.
+func (q *CrossPartitionQuery) SetContinuation(continuation string) *CrossPartitionQuery {
+	q.continuation = continuation
+	return q
+}
+
+// SetMaxDegreeOfParallelism - reads the partition key ranges concurrently with at most n requests in flight, one
+// per partition key range when n is negative. Results of queries without ORDER BY are returned in the order their
+// pages arrive. A parallel query must be closed when it is abandoned before it is done.
+//	query := client.NewCrossPartitionQuery(coll, q, 100).SetMaxDegreeOfParallelism(-1).SetPrefetch(2)
+//	defer query.Close()
+func (q *CrossPartitionQuery) SetMaxDegreeOfParallelism(n int) *CrossPartitionQuery {
+	q.parallelism = n
+	return q
+}
+
+// SetPrefetch - sets the number of pages of every partition key range read ahead of the results returned by a
+// parallel query, reading a range pauses while its pages are not consumed. Defaults to 1.
+func (q *CrossPartitionQuery) SetPrefetch(pages int) *CrossPartitionQuery {
+	q.prefetch = pages
+	return q
+}
+
+// Close - stops reading the partition key ranges of a parallel query, the query is closed once it is done.
+// The continuation token stays valid.
+func (q *CrossPartitionQuery) Close() {
+	if q.cancel != nil {
+		q.cancel()
+	}
+	if slow := q.client.Config.SlowQueryLog; slow != nil && q.activityID != "" {
+		slow.complete(q.activityID)
+	}
+}
+
+// Continuation - returns the token resuming the query after the last page, empty when the query is done. An
+// unordered DISTINCT query that returned more than 1000 results has no token, it has to be read to the end.
+func (q *CrossPartitionQuery) Continuation() (string, error) {
+	if q.done || q.source == nil {
+		return q.continuation, nil
+	}
+	token := &crossPartitionToken{}
+	q.source.state(token)
+	if token.Distinct != nil && len(token.Distinct.Seen) > maxDistinctContinuation {
+		return "", &ValidationError{fmt.Sprintf("the continuation token of an unordered DISTINCT query holds at most %d results, add an ORDER BY or read the query to the end", maxDistinctContinuation)}
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// Done - returns true if no more pages are available
+func (q *CrossPartitionQuery) Done() bool {
+	return q.done
+}
+
+// RequestCharge - returns the request units consumed by the query so far
+func (q *CrossPartitionQuery) RequestCharge() float64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.charge
+}
+
+// Next - marshals the next page of results into the passed interface
+func (q *CrossPartitionQuery) Next(docs interface{}) error {
+	if q.done {
+		return nil
+	}
+	if q.source == nil {
+		if err := q.start(); err != nil {
+			return err
+		}
+	}
+	page := []json.RawMessage{}
+	for q.pageSize <= 0 || len(page) < q.pageSize {
+		item, ok, err := q.source.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			q.done, q.continuation = true, ""
+			break
+		}
+		page = append(page, item)
+	}
+	if !q.done {
+		// look ahead so the last page is known to be the last
+		if _, ok, err := q.source.peek(); err != nil {
+			return err
+		} else if !ok {
+			q.done, q.continuation = true, ""
+		}
+	}
+	if q.done {
+		q.Close()
+	}
+	data, err := json.Marshal(page)
+	if err != nil {
+		return err
+	}
+	return q.client.client.decode(bytes.NewReader(data), docs)
+}
+
+// start - fetches the query plan and the partition key ranges, or restores them from the continuation token
+func (q *CrossPartitionQuery) start() error {
+	if q.query == nil {
+		return &ValidationError{"QueryWithParameters cannot be nil"}
+	}
+	// the plan, the partition key ranges and every page of every range are one logical operation
+	r := optionsRequest(q.opts)
+	if q.activityID = r.Header.Get(HeaderActivityID); q.activityID == "" {
+		if q.activityID = ActivityIDFromContext(r.context()); q.activityID == "" {
+			q.activityID = genId()
+		}
+	}
+	q.opts = append(q.opts[:len(q.opts):len(q.opts)], ActivityID(q.activityID))
+	// the paging, session and partition key options are for the pages of the ranges, the plan and the partition
+	// key ranges are read whole
+	meta := []CallOption{ActivityID(q.activityID), WithContext(r.context())}
+	plan, resp, err := q.client.client.queryPlan(q.coll+"docs/", q.query, meta...)
+	q.addCharge(resp)
+	if err != nil {
+		return err
+	}
+	q.plan = plan
+	token := &crossPartitionToken{}
+	if q.continuation != "" {
+		if token, err = decodeCrossPartitionToken(q.continuation); err != nil {
+			return err
+		}
+	}
+	pkRanges, feed, err := q.client.QueryAllPartitionKeyRanges(q.coll, "", 0, meta...)
+	if feed != nil {
+		q.mu.Lock()
+		q.charge += feed.RequestCharge
+		q.mu.Unlock()
+	}
+	if err != nil {
+		return err
+	}
+	if q.continuation != "" {
+		if err = token.validateRanges(pkRanges); err != nil {
+			return err
+		}
+	} else {
+		for _, pkRange := range plan.targetRanges(pkRanges) {
+			token.Ranges = append(token.Ranges, rangeState{ID: pkRange.Id})
+		}
+		plan.QueryInfo.initialize(token)
+	}
+	cursors := make([]*rangeCursor, len(token.Ranges))
+	for i, state := range token.Ranges {
+		cursors[i] = &rangeCursor{query: q, state: state, rewritten: plan.rewrittenQuery(q.query)}
+	}
+	info := plan.QueryInfo
+	if q.parallelism != 0 {
+		q.prefetchRanges(cursors)
+	}
+	switch {
+	case len(info.OrderBy) > 0:
+		for _, c := range cursors {
+			c.orderBy = true
+		}
+		q.source = &orderByMerge{cursors: cursors, orders: info.OrderBy}
+	case q.parallelism != 0:
+		q.source = &parallelSource{cursors: cursors}
+	default:
+		q.source = &sequential{cursors: cursors}
+	}
+	q.source = info.pipeline(q.source, token)
+	return nil
+}
+
+// addCharge - adds the request charge of a response to the total of the query
+func (q *CrossPartitionQuery) addCharge(resp *Response) {
+	if resp == nil {
+		return
+	}
+	if charge, err := resp.GetRUs(); err == nil {
+		q.mu.Lock()
+		q.charge += charge
+		q.mu.Unlock()
+	}
+}
+
+// crossPartitionToken - the continuation token of a cross partition query, the positions in the partition key
+// ranges and the state of the stages processing their results
+type crossPartitionToken struct {
+	Ranges   []rangeState   `json:"ranges"`
+	Emitted  int            `json:"emitted,omitempty"`
+	Distinct *distinctState `json:"distinct,omitempty"`
+	Offset   *int           `json:"offset,omitempty"`
+	Limit    *int           `json:"limit,omitempty"`
+	Top      *int           `json:"top,omitempty"`
+}
+
+// decodeCrossPartitionToken - decodes a continuation token returned by CrossPartitionQuery.Continuation
+func decodeCrossPartitionToken(continuation string) (*crossPartitionToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(continuation)
+	if err != nil {
+		return nil, &ValidationError{"invalid cross-partition continuation token"}
+	}
+	token := &crossPartitionToken{}
+	if err = json.Unmarshal(data, token); err != nil || len(token.Ranges) == 0 {
+		return nil, &ValidationError{"invalid cross-partition continuation token"}
+	}
+	return token, nil
+}
+
+// validateRanges - checks the partition key ranges of a token still exist. A range split since the token was
+// returned is not resumed, the positions in its children are unknown and the query has to be restarted.
+func (t *crossPartitionToken) validateRanges(pkRanges []PartitionKeyRange) error {
+	ids := map[string]bool{}
+	for _, pkRange := range pkRanges {
+		ids[pkRange.Id] = true
+	}
+	for _, state := range t.Ranges {
+		if !state.Done && !ids[state.ID] {
+			return &RangeGoneError{state.ID}
+		}
+	}
+	return nil
+}
+
+// RangeGoneError - returned when a partition key range of a continuation token has been split since the token
+// was returned, the query has to be restarted
+type RangeGoneError struct {
+	PartitionKeyRange string
+}
+
+// Error - implements the error interface
+func (e *RangeGoneError) Error() string {
+	return "partition key range " + e.PartitionKeyRange + " of the continuation token no longer exists, the collection was split: restart the query"
+}
+
+// Is - matches ErrPartitionGone
+func (e *RangeGoneError) Is(target error) bool {
+	return target == ErrPartitionGone
+}
+
+// querySource - yields the results of a query merged across partition key ranges
+type querySource interface {
+	peek() (json.RawMessage, bool, error)
+	next() (json.RawMessage, bool, error)
+	state(token *crossPartitionToken)
+}
+
+// rangeState - the position of a query in a partition key range. Token is the continuation the buffered page was
+// fetched with, Skip the number of its results consumed and RID the _rid of the last one.
+type rangeState struct {
+	ID    string `json:"id"`
+	Token string `json:"token,omitempty"`
+	Skip  int    `json:"skip,omitempty"`
+	RID   string `json:"rid,omitempty"`
+	Done  bool   `json:"done,omitempty"`
+}
+
+// rangeCursor - reads the results of a query in a partition key range page by page
+type rangeCursor struct {
+	query     *CrossPartitionQuery
+	rewritten *QueryWithParameters
+	state     rangeState
+	orderBy   bool
+	fetched   bool
+	page      []json.RawMessage
+	pos       int
+	nextToken string
+	current   *orderByResult
+	pages     chan rangePage
+}
+
+// orderByResult - a result of a rewritten ORDER BY query
+type orderByResult struct {
+	RID          string                       `json:"_rid"`
+	OrderByItems []map[string]json.RawMessage `json:"orderByItems"`
+	Payload      json.RawMessage              `json:"payload"`
+	values       []interface{}
+}
+
+// fetch - reads the page of results starting at token, or receives it from the pages prefetched in parallel
+func (c *rangeCursor) fetch(token string) error {
+	if c.pages != nil {
+		p, ok := <-c.pages
+		return c.receive(p, ok)
+	}
+	return c.accept(c.query.load(c.state.ID, c.rewritten, token))
+}
+
+// accept - makes a page the current one, the first page fetched skips the results a continuation token already
+// returned
+func (c *rangeCursor) accept(p rangePage) error {
+	if p.err != nil {
+		return p.err
+	}
+	restoring, skip, rid := !c.fetched, c.state.Skip, c.state.RID
+	c.page, c.pos, c.nextToken, c.current = p.docs, 0, p.next, nil
+	c.state.Token, c.state.Skip, c.state.RID, c.fetched = p.token, 0, "", true
+	if !restoring || (skip == 0 && rid == "") {
+		return nil
+	}
+	c.pos = skip
+	if rid != "" && c.orderBy {
+		// the service may page differently this time, find the last returned result by its _rid
+		for i, raw := range c.page {
+			result := orderByResult{}
+			if json.Unmarshal(raw, &result) == nil && result.RID == rid {
+				c.pos = i + 1
+				break
+			}
+		}
+	}
+	if c.pos > len(c.page) {
+		c.pos = len(c.page)
+	}
+	c.state.Skip, c.state.RID = c.pos, rid
+	return nil
+}
+
+// peek - returns the current result without consuming it, false once the range is exhausted
+func (c *rangeCursor) peek() (json.RawMessage, bool, error) {
+	if c.state.Done {
+		return nil, false, nil
+	}
+	for !c.fetched || c.pos >= len(c.page) {
+		if c.fetched && c.nextToken == "" {
+			c.state.Done = true
+			return nil, false, nil
+		}
+		token := c.nextToken
+		if !c.fetched {
+			token = c.state.Token
+		}
+		if err := c.fetch(token); err != nil {
+			return nil, false, err
+		}
+	}
+	return c.page[c.pos], true, nil
+}
+
+// pop - consumes the current result
+func (c *rangeCursor) pop() {
+	if c.current != nil {
+		c.state.RID = c.current.RID
+	}
+	c.pos++
+	c.state.Skip = c.pos
+	c.current = nil
+}
+
+// result - returns the current result of an ORDER BY query with its order by values decoded
+func (c *rangeCursor) result() (*orderByResult, bool, error) {
+	if c.current != nil {
+		return c.current, true, nil
+	}
+	raw, ok, err := c.peek()
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	result := &orderByResult{}
+	if err = json.Unmarshal(raw, result); err != nil {
+		return nil, false, err
+	}
+	result.values = make([]interface{}, len(result.OrderByItems))
+	for i, item := range result.OrderByItems {
+		result.values[i] = undefined{}
+		if v, ok := item["item"]; ok {
+			if err = json.Unmarshal(v, &result.values[i]); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+	c.current = result
+	return result, true, nil
+}
+
+// sequential - yields the results of every partition key range in turn
+type sequential struct {
+	cursors []*rangeCursor
+}
+
+func (s *sequential) peek() (json.RawMessage, bool, error) {
+	for _, c := range s.cursors {
+		raw, ok, err := c.peek()
+		if err != nil || ok {
+			return raw, ok, err
+		}
+	}
+	return nil, false, nil
+}
+
+func (s *sequential) next() (json.RawMessage, bool, error) {
+	for _, c := range s.cursors {
+		raw, ok, err := c.peek()
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			c.pop()
+			return raw, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (s *sequential) state(token *crossPartitionToken) {
+	token.Ranges = cursorStates(s.cursors)
+}
+
+// orderByMerge - merges the ordered results of every partition key range
+type orderByMerge struct {
+	cursors []*rangeCursor
+	orders  []string
+}
+
+// min - returns the cursor holding the first result in the query order, ties go to the lower range
+func (m *orderByMerge) min() (*rangeCursor, *orderByResult, error) {
+	var first *rangeCursor
+	var firstResult *orderByResult
+	for _, c := range m.cursors {
+		result, ok, err := c.result()
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			continue
+		}
+		if first == nil || m.less(result, firstResult) {
+			first, firstResult = c, result
+		}
+	}
+	return first, firstResult, nil
+}
+
+// less - whether a sorts before b
+func (m *orderByMerge) less(a, b *orderByResult) bool {
+	for i := range a.values {
+		if i >= len(b.values) {
+			break
+		}
+		cmp := compareValues(a.values[i], b.values[i])
+		if i < len(m.orders) && m.orders[i] == "Descending" {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp < 0
+		}
+	}
+	return false
+}
+
+func (m *orderByMerge) peek() (json.RawMessage, bool, error) {
+	c, result, err := m.min()
+	if c == nil || err != nil {
+		return nil, false, err
+	}
+	return result.Payload, true, nil
+}
+
+func (m *orderByMerge) next() (json.RawMessage, bool, error) {
+	c, result, err := m.min()
+	if c == nil || err != nil {
+		return nil, false, err
+	}
+	c.pop()
+	return result.Payload, true, nil
+}
+
+func (m *orderByMerge) state(token *crossPartitionToken) {
+	token.Ranges = cursorStates(m.cursors)
+}
+
+// cursorStates - returns the positions of the cursors
+func cursorStates(cursors []*rangeCursor) []rangeState {
+	states := make([]rangeState, len(cursors))
+	for i, c := range cursors {
+		states[i] = c.state
+	}
+	return states
+}
+
+// undefined - the value of a missing property, it sorts before every other value
+type undefined struct{}
+
+// typeOrder - the order of the types of values in an ORDER BY
+func typeOrder(v interface{}) int {
+	switch v.(type) {
+	case undefined:
+		return 0
+	case nil:
+		return 1
+	case bool:
+		return 2
+	case float64:
+		return 3
+	case string:
+		return 4
+	case []interface{}:
+		return 5
+	}
+	return 6
+}
+
+// compareValues - compares two JSON values the way Cosmos DB orders them, values of different types are
+// ordered by type: undefined, null, booleans, numbers, strings, arrays and objects
+func compareValues(a, b interface{}) int {
+	ta, tb := typeOrder(a), typeOrder(b)
+	if ta != tb {
+		return ta - tb
+	}
+	switch a := a.(type) {
+	case bool:
+		if a == b.(bool) {
+			return 0
+		} else if !a {
+			return -1
+		}
+		return 1
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b || (math.IsNaN(a) && !math.IsNaN(b)):
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case string:
+		b := b.(string)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	}
+	return 0
+}
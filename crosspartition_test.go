@@ -0,0 +1,285 @@
+package gocosmosdb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// partitionedServer - serves a query plan, the partition key ranges and the results of the rewritten query per range,
+// paged by the max item count with the offset in the range as continuation. Queries of the slow range are delayed.
+// The partition key ranges are served in pages of rangesPerPage when it is set.
+type partitionedServer struct {
+	*httptest.Server
+	mu            sync.Mutex
+	queries       []string
+	activityIDs   map[string]int
+	slow          string
+	delay         time.Duration
+	inFlight      int
+	maxInFlight   int
+	rangesPerPage int
+}
+
+func newPartitionedServer(plan string, results map[string][]string) *partitionedServer {
+	s := &partitionedServer{activityIDs: map[string]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderRequestCharge, "1")
+		s.mu.Lock()
+		s.activityIDs[r.Header.Get(HeaderActivityID)]++
+		s.mu.Unlock()
+		if r.Header.Get(HeaderIsQueryPlan) == "true" {
+			fmt.Fprint(w, plan)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/pkranges/") {
+			ranges := []string{}
+			bounds := []string{"", "55", "AA", "FF"}
+			for i := 0; i < len(results); i++ {
+				ranges = append(ranges, fmt.Sprintf(`{"id": "%d", "minInclusive": "%s", "maxExclusive": "%s"}`, i, bounds[i], bounds[i+1]))
+			}
+			if start, _ := strconv.Atoi(r.Header.Get(HeaderContinuation)); s.rangesPerPage > 0 {
+				ranges = ranges[start:]
+				if len(ranges) > s.rangesPerPage {
+					ranges = ranges[:s.rangesPerPage]
+					w.Header().Set(HeaderContinuation, strconv.Itoa(start+s.rangesPerPage))
+				}
+			}
+			fmt.Fprintf(w, `{"PartitionKeyRanges": [%s]}`, strings.Join(ranges, ","))
+			return
+		}
+		query := QueryWithParameters{}
+		json.NewDecoder(r.Body).Decode(&query)
+		s.mu.Lock()
+		s.queries = append(s.queries, query.Query)
+		s.inFlight++
+		if s.inFlight > s.maxInFlight {
+			s.maxInFlight = s.inFlight
+		}
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			s.inFlight--
+			s.mu.Unlock()
+		}()
+		rangeID := r.Header.Get(HeaderPartitionKeyRangeID)
+		if s.slow == "" || s.slow == rangeID {
+			time.Sleep(s.delay)
+		}
+		docs := results[rangeID]
+		start, _ := strconv.Atoi(r.Header.Get(HeaderContinuation))
+		end := len(docs)
+		if max, err := strconv.Atoi(r.Header.Get(HeaderMaxItemCount)); err == nil && max > 0 && start+max < end {
+			end = start + max
+			w.Header().Set(HeaderContinuation, strconv.Itoa(end))
+		}
+		fmt.Fprintf(w, `{"Documents": [%s]}`, strings.Join(docs[start:end], ","))
+	}))
+	return s
+}
+
+// peak - returns the most queries in flight at once and resets it
+func (s *partitionedServer) peak() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	peak := s.maxInFlight
+	s.maxInFlight = 0
+	return peak
+}
+
+// orderByDoc - a result of a rewritten ORDER BY query on name
+func orderByDoc(rid string, name interface{}) string {
+	item := `{}`
+	if name != nil {
+		v, _ := json.Marshal(name)
+		item = fmt.Sprintf(`{"item": %s}`, v)
+	}
+	return fmt.Sprintf(`{"_rid": "%s", "orderByItems": [%s], "payload": {"id": "%s"}}`, rid, item, rid)
+}
+
+const orderByPlan = `{"partitionedQueryExecutionInfoVersion": 2, "queryInfo": {"distinctType": "None", "orderBy": ["Ascending"],
+	"orderByExpressions": ["c.name"], "rewrittenQuery": "SELECT c._rid, [{\"item\": c.name}] AS orderByItems, c AS payload FROM c WHERE ({documentdb-formattableorderbyquery-filter}) ORDER BY c.name"},
+	"queryRanges": [{"min": "", "max": "FF", "isMinInclusive": true, "isMaxInclusive": false}]}`
+
+func orderByResults() map[string][]string {
+	return map[string][]string{
+		"0": {orderByDoc("u", nil), orderByDoc("a", "ann"), orderByDoc("d", "dan"), orderByDoc("f", "fay")},
+		"1": {orderByDoc("b", "bob"), orderByDoc("e", "eve")},
+		"2": {orderByDoc("c", "cat"), orderByDoc("g", "gus"), orderByDoc("h", "hal")},
+	}
+}
+
+func ids(docs []Document) string {
+	s := ""
+	for _, d := range docs {
+		s += d.Id
+	}
+	return s
+}
+
+func TestCrossPartitionOrderBy(t *testing.T) {
+	assert := assert.New(t)
+	s := newPartitionedServer(orderByPlan, orderByResults())
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().OrderBy("c.name").Build(), 3)
+	pages := []string{}
+	for !query.Done() {
+		docs := []Document{}
+		assert.Nil(query.Next(&docs))
+		pages = append(pages, ids(docs))
+	}
+	assert.Equal([]string{"uab", "cde", "fgh"}, pages)
+	continuation, err := query.Continuation()
+	assert.Nil(err)
+	assert.Equal("", continuation)
+	assert.True(query.RequestCharge() > 0)
+	assert.Contains(s.queries[0], "WHERE (true) ORDER BY c.name")
+}
+
+func TestCrossPartitionActivityID(t *testing.T) {
+	assert := assert.New(t)
+	s := newPartitionedServer(orderByPlan, orderByResults())
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().OrderBy("c.name").Build(), 2)
+	for !query.Done() {
+		assert.Nil(query.Next(&[]Document{}))
+	}
+	// the plan, the ranges and the 5 pages of the 3 ranges share one activity ID
+	assert.Len(s.activityIDs, 1)
+	for id, requests := range s.activityIDs {
+		assert.NotEmpty(id)
+		assert.Equal(7, requests)
+	}
+
+	// the activity ID of the context is used
+	s.activityIDs = map[string]int{}
+	ctx := WithActivityID(context.Background(), "query-activity")
+	query = client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().OrderBy("c.name").Build(), 0, WithContext(ctx)).SetMaxDegreeOfParallelism(-1)
+	assert.Nil(query.Next(&[]Document{}))
+	assert.Equal(map[string]int{"query-activity": 5}, s.activityIDs)
+}
+
+func TestCrossPartitionPagedRanges(t *testing.T) {
+	assert := assert.New(t)
+	s := newPartitionedServer(orderByPlan, orderByResults())
+	s.rangesPerPage = 2
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+
+	// the ranges on the second page are queried and the paging options of the query are not used to read them
+	query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().OrderBy("c.name").Build(), 0, Limit(1))
+	docs := []Document{}
+	assert.Nil(query.Next(&docs))
+	assert.Equal("uabcdefgh", ids(docs))
+	assert.True(query.Done())
+	// the plan, the 2 pages of ranges and the 9 pages of one result of the ranges
+	assert.Equal(map[string]int{query.activityID: 12}, s.activityIDs)
+}
+
+func TestCrossPartitionOrderByContinuation(t *testing.T) {
+	assert := assert.New(t)
+	s := newPartitionedServer(orderByPlan, orderByResults())
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().OrderBy("c.name").Build(), 2)
+	pages := []string{}
+	for !query.Done() {
+		docs := []Document{}
+		assert.Nil(query.Next(&docs))
+		pages = append(pages, ids(docs))
+		// resume every page from the continuation token with a new query
+		continuation, err := query.Continuation()
+		assert.Nil(err)
+		query = client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().OrderBy("c.name").Build(), 2).SetContinuation(continuation)
+		if continuation == "" {
+			break
+		}
+	}
+	assert.Equal([]string{"ua", "bc", "de", "fg", "h"}, pages)
+
+	_, err := decodeCrossPartitionToken("garbage")
+	assert.True(IsValidationError(err))
+}
+
+func TestCrossPartitionContinuationAfterSplit(t *testing.T) {
+	assert := assert.New(t)
+	s := newPartitionedServer(orderByPlan, orderByResults())
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().OrderBy("c.name").Build(), 2)
+	assert.Nil(query.Next(&[]Document{}))
+
+	// range 2 is gone from the collection the query resumes on
+	results := orderByResults()
+	delete(results, "2")
+	split := newPartitionedServer(orderByPlan, results)
+	defer split.Close()
+	client = New(split.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	continuation, err := query.Continuation()
+	assert.Nil(err)
+	query = client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().OrderBy("c.name").Build(), 2).SetContinuation(continuation)
+	err = query.Next(&[]Document{})
+	assert.True(IsPartitionGone(err))
+	assert.False(IsValidationError(err))
+	gone := &RangeGoneError{}
+	if assert.True(errors.As(err, &gone)) {
+		assert.Equal("2", gone.PartitionKeyRange)
+	}
+}
+
+func TestCrossPartitionSequential(t *testing.T) {
+	assert := assert.New(t)
+	plan := `{"queryInfo": {"distinctType": "None", "rewrittenQuery": ""}, "queryRanges": [{"min": "", "max": "FF", "isMinInclusive": true}]}`
+	s := newPartitionedServer(plan, map[string][]string{
+		"0": {`{"id": "a"}`, `{"id": "b"}`},
+		"1": {},
+		"2": {`{"id": "c"}`},
+	})
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().Build(), 0)
+	docs := []Document{}
+	assert.Nil(query.Next(&docs))
+	assert.Equal("abc", ids(docs))
+	assert.True(query.Done())
+	assert.Equal("SELECT * FROM c", s.queries[0])
+}
+
+func TestQueryPlanTargetRanges(t *testing.T) {
+	assert := assert.New(t)
+	pkRanges := []PartitionKeyRange{
+		{Resource: Resource{Id: "1"}, MinInclusive: "55", MaxInclusive: "AA"},
+		{Resource: Resource{Id: "0"}, MinInclusive: "", MaxInclusive: "55"},
+		{Resource: Resource{Id: "2"}, MinInclusive: "AA", MaxInclusive: "FF"},
+	}
+	plan := &queryPlan{QueryRanges: []queryRange{{Min: "60", Max: "60", IsMinInclusive: true, IsMaxInclusive: true}}}
+	targets := plan.targetRanges(pkRanges)
+	assert.Len(targets, 1)
+	assert.Equal("1", targets[0].Id)
+	plan = &queryPlan{QueryRanges: []queryRange{{Min: "", Max: "AA", IsMinInclusive: true}}}
+	targets = plan.targetRanges(pkRanges)
+	assert.Len(targets, 2)
+	assert.Equal("0", targets[0].Id)
+}
+
+func TestCompareValues(t *testing.T) {
+	assert := assert.New(t)
+	ordered := []interface{}{undefined{}, nil, false, true, -1.5, 2.0, "a", "b", []interface{}{}, map[string]interface{}{}}
+	for i := 0; i < len(ordered)-1; i++ {
+		assert.True(compareValues(ordered[i], ordered[i+1]) < 0, "%v < %v", ordered[i], ordered[i+1])
+		assert.True(compareValues(ordered[i+1], ordered[i]) > 0)
+		assert.Equal(0, compareValues(ordered[i], ordered[i]))
+	}
+}
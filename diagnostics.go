@@ -0,0 +1,125 @@
+package gocosmosdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Diagnostics - details of a single call, attached to its Response or RequestError
+type Diagnostics struct {
+	Latency           time.Duration `json:"latency"`
+	Retries           int           `json:"retries"`
+	RetryReasons      []string      `json:"retryReasons,omitempty"`
+	Endpoint          string        `json:"endpoint"`
+	Region            string        `json:"region,omitempty"`
+	ActivityID        string        `json:"activityId,omitempty"`
+	StatusCode        int           `json:"statusCode"`
+	SubStatus         int           `json:"subStatus,omitempty"`
+	RequestCharge     float64       `json:"requestCharge"`
+	RequestSize       int64         `json:"requestSize"`
+	ResponseSize      int64         `json:"responseSize"`
+	PartitionKeyRange string        `json:"partitionKeyRange,omitempty"`
+	start             time.Time
+	lastFailure       string
+}
+
+// String - formats the diagnostics as a single log line
+func (d *Diagnostics) String() string {
+	retries := strconv.Itoa(d.Retries)
+	if len(d.RetryReasons) > 0 {
+		retries += " (" + strings.Join(d.RetryReasons, ", ") + ")"
+	}
+	return fmt.Sprintf("status=%d substatus=%d latency=%s retries=%s endpoint=%s region=%s activityId=%s ru=%.2f requestBytes=%d responseBytes=%d pkRange=%s",
+		d.StatusCode, d.SubStatus, d.Latency, retries, d.Endpoint, d.Region, d.ActivityID,
+		d.RequestCharge, d.RequestSize, d.ResponseSize, d.PartitionKeyRange)
+}
+
+type diagnosticsKey struct{}
+
+// newDiagnostics - starts the diagnostics of a request and adds them to its context
+func newDiagnostics(r *Request) *Diagnostics {
+	d := &Diagnostics{
+		Endpoint:    r.URL.Host,
+		Region:      regionFromHost(r.URL.Hostname()),
+		ActivityID:  r.Header.Get(HeaderActivityID),
+		RequestSize: r.ContentLength,
+		start:       time.Now(),
+	}
+	r.rContext = context.WithValue(r.context(), diagnosticsKey{}, d)
+	return d
+}
+
+// diagnosticsFromContext - returns the diagnostics of the request a context belongs to, if any
+func diagnosticsFromContext(ctx context.Context) *Diagnostics {
+	if ctx == nil {
+		return nil
+	}
+	d, _ := ctx.Value(diagnosticsKey{}).(*Diagnostics)
+	return d
+}
+
+// response - records the details of a response
+func (d *Diagnostics) response(resp *http.Response) {
+	d.StatusCode = resp.StatusCode
+	if id := resp.Header.Get(HeaderActivityID); id != "" {
+		d.ActivityID = id
+	}
+	d.SubStatus, _ = strconv.Atoi(resp.Header.Get(HeaderSubStatus))
+	d.RequestCharge, _ = strconv.ParseFloat(resp.Header.Get(HeaderRequestCharge), 64)
+	d.PartitionKeyRange = resp.Header.Get(HeaderPartitionKeyRangeID)
+	if resp.ContentLength >= 0 {
+		d.ResponseSize = resp.ContentLength
+	}
+}
+
+// finish - records the total latency of the call
+func (d *Diagnostics) finish() *Diagnostics {
+	d.Latency = time.Since(d.start)
+	return d
+}
+
+// retrying - records a failed attempt that will be retried
+func (d *Diagnostics) retrying(resp *http.Response, err error) {
+	switch {
+	case err != nil:
+		d.lastFailure = err.Error()
+	case resp != nil:
+		d.lastFailure = strconv.Itoa(resp.StatusCode)
+	}
+}
+
+// retry - records that a retry is being sent
+func (d *Diagnostics) retry() {
+	d.Retries++
+	d.RetryReasons = append(d.RetryReasons, d.lastFailure)
+	d.lastFailure = ""
+}
+
+// regionFromHost - returns the region of a regional endpoint eg. "westus" for myaccount-westus.documents.azure.com
+func regionFromHost(host string) string {
+	if !strings.HasSuffix(host, ".documents.azure.com") {
+		return ""
+	}
+	account := strings.SplitN(host, ".", 2)[0]
+	if i := strings.LastIndex(account, "-"); i > -1 {
+		return account[i+1:]
+	}
+	return ""
+}
+
+// countingReader - counts the bytes read from a response body without a content length
+type countingReader struct {
+	io.Reader
+	n *int64
+}
+
+func (r countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	*r.n += int64(n)
+	return n, err
+}
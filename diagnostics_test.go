@@ -0,0 +1,90 @@
+package gocosmosdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnostics(t *testing.T) {
+	assert := assert.New(t)
+	attempts := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set(HeaderActivityID, "a1b2")
+		w.Header().Set(HeaderRequestCharge, "2.5")
+		w.Header().Set(HeaderPartitionKeyRangeID, "3")
+		if attempts == 3 {
+			w.Header().Set(HeaderSubStatus, "1002")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code": "NotFound", "message": "missing"}`)
+			return
+		}
+		fmt.Fprint(w, `{"id": "9"}`)
+	}))
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", RetryMax: 1, RetryWaitMax: time.Millisecond}, log)
+
+	doc := Document{}
+	resp, err := client.ReadDocument("dbs/mydb/colls/mycoll/docs/9", &doc)
+	assert.Nil(err)
+	d := resp.Diagnostics
+	assert.Equal(http.StatusOK, d.StatusCode)
+	assert.Equal(1, d.Retries)
+	assert.Equal([]string{"503"}, d.RetryReasons)
+	assert.Equal("a1b2", d.ActivityID)
+	assert.Equal(2.5, d.RequestCharge)
+	assert.Equal("3", d.PartitionKeyRange)
+	assert.Equal(s.Listener.Addr().String(), d.Endpoint)
+	assert.Equal(int64(11), d.ResponseSize)
+	assert.True(d.Latency > 0)
+
+	_, err = client.ReadDocument("dbs/mydb/colls/mycoll/docs/10", &doc)
+	reqErr, ok := err.(*RequestError)
+	assert.True(ok)
+	assert.Equal(http.StatusNotFound, reqErr.Diagnostics.StatusCode)
+	assert.Equal(1002, reqErr.Diagnostics.SubStatus)
+	assert.Equal(0, reqErr.Diagnostics.Retries)
+	assert.Contains(err.Error(), "NotFound, missing (status=404 substatus=1002")
+	assert.Contains(err.Error(), "activityId=a1b2 ru=2.50")
+}
+
+func TestDiagnosticsRequestSize(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(`{"id": "9"}`)
+	s.SetStatus(http.StatusCreated)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	doc := Document{}
+	doc.Id = "9"
+	resp, err := client.CreateDocument("dbs/mydb/colls/mycoll/", &doc)
+	assert.Nil(err)
+	assert.Equal(int64(len(s.Body)), resp.Diagnostics.RequestSize)
+}
+
+func TestRegionFromHost(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("westus", regionFromHost("myaccount-westus.documents.azure.com"))
+	assert.Equal("", regionFromHost("myaccount.documents.azure.com"))
+	assert.Equal("", regionFromHost("localhost"))
+}
+
+func TestActivityIDFromContext(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(404)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	ctx := WithActivityID(context.Background(), "")
+	_, err := client.ReadDatabase("dbs/qicAAA==", WithContext(ctx))
+	assert.Equal(ActivityIDFromContext(ctx), s.Header.Get(HeaderActivityID))
+	assert.Equal(ActivityIDFromContext(ctx), err.(*RequestError).ActivityID)
+}
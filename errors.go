@@ -0,0 +1,146 @@
+package gocosmosdb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrNotFound - matched by errors.Is for 404 responses
+	ErrNotFound = errors.New("resource not found")
+
+	// ErrConflict - matched by errors.Is for 409 responses
+	ErrConflict = errors.New("resource conflict")
+
+	// ErrPreconditionFailed - matched by errors.Is for 412 responses, eg. an etag mismatch
+	ErrPreconditionFailed = errors.New("precondition failed")
+
+	// ErrThrottled - matched by errors.Is for 429 responses
+	ErrThrottled = errors.New("request rate too large")
+
+	// ErrPartitionGone - matched by errors.Is for 410 responses, eg. after a partition split
+	ErrPartitionGone = errors.New("partition gone")
+
+	// ErrTimeout - matched by errors.Is for 408 responses and requests that timed out
+	ErrTimeout = errors.New("request timeout")
+
+	// ErrValidation - matched by errors.Is for requests rejected by the client before they were sent
+	ErrValidation = errors.New("validation failed")
+)
+
+// NetworkError - a request failed without a response from the service, eg. a connection error or timeout
+type NetworkError struct {
+	Err error
+}
+
+// Error - implements the error interface
+func (e *NetworkError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap - returns the underlying error
+func (e *NetworkError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout - returns true if the request timed out
+func (e *NetworkError) Timeout() bool {
+	if errors.Is(e.Err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(e.Err, &netErr) && netErr.Timeout()
+}
+
+// Is - matches ErrTimeout for timeouts
+func (e *NetworkError) Is(target error) bool {
+	return target == ErrTimeout && e.Timeout()
+}
+
+// ValidationError - a request was rejected by the client before it was sent
+type ValidationError struct {
+	Message string
+}
+
+// Error - implements the error interface
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// Is - matches ErrValidation
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
+
+// Is - matches the sentinel error for the status of the response
+func (e RequestError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrPreconditionFailed:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrThrottled:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrPartitionGone:
+		return e.StatusCode == http.StatusGone
+	case ErrTimeout:
+		return e.StatusCode == http.StatusRequestTimeout
+	}
+	return false
+}
+
+// retryAfter - returns how long the service asked the client to wait before retrying
+func retryAfter(header http.Header) time.Duration {
+	ms, err := strconv.ParseFloat(header.Get(HeaderRetryAfterMs), 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// IsNotFound - returns true if the resource does not exist
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsConflict - returns true if a resource with the same id already exists
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// IsPreconditionFailed - returns true if a conditional request did not match, eg. an etag mismatch
+func IsPreconditionFailed(err error) bool {
+	return errors.Is(err, ErrPreconditionFailed)
+}
+
+// IsThrottled - returns true if the request was rate limited, see RequestError.RetryAfter
+func IsThrottled(err error) bool {
+	return errors.Is(err, ErrThrottled)
+}
+
+// IsPartitionGone - returns true if the partition serving the request has moved, eg. after a split
+func IsPartitionGone(err error) bool {
+	return errors.Is(err, ErrPartitionGone)
+}
+
+// IsTimeout - returns true if the request timed out on the client or the service
+func IsTimeout(err error) bool {
+	return errors.Is(err, ErrTimeout)
+}
+
+// IsNetworkError - returns true if the request failed without a response from the service
+func IsNetworkError(err error) bool {
+	var netErr *NetworkError
+	return errors.As(err, &netErr)
+}
+
+// IsValidationError - returns true if the request was rejected before it was sent
+func IsValidationError(err error) bool {
+	return errors.Is(err, ErrValidation)
+}
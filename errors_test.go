@@ -0,0 +1,109 @@
+package gocosmosdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestErrorIs(t *testing.T) {
+	assert := assert.New(t)
+	statuses := map[int]error{
+		http.StatusNotFound:           ErrNotFound,
+		http.StatusConflict:           ErrConflict,
+		http.StatusPreconditionFailed: ErrPreconditionFailed,
+		http.StatusTooManyRequests:    ErrThrottled,
+		http.StatusGone:               ErrPartitionGone,
+		http.StatusRequestTimeout:     ErrTimeout,
+	}
+	for status, sentinel := range statuses {
+		var err error = &RequestError{StatusCode: status}
+		wrapped := fmt.Errorf("reading order: %w", err)
+		assert.True(errors.Is(wrapped, sentinel), "status %d", status)
+		for _, other := range statuses {
+			if other != sentinel {
+				assert.False(errors.Is(err, other), "status %d", status)
+			}
+		}
+		var reqErr *RequestError
+		assert.True(errors.As(wrapped, &reqErr))
+		assert.Equal(status, reqErr.StatusCode)
+	}
+	assert.True(IsNotFound(&RequestError{StatusCode: http.StatusNotFound}))
+	assert.True(IsConflict(&RequestError{StatusCode: http.StatusConflict}))
+	assert.True(IsPreconditionFailed(&RequestError{StatusCode: http.StatusPreconditionFailed}))
+	assert.True(IsThrottled(&RequestError{StatusCode: http.StatusTooManyRequests}))
+	assert.True(IsPartitionGone(&RequestError{StatusCode: http.StatusGone, SubStatus: 1002}))
+	assert.False(IsNotFound(errors.New("not found")))
+	assert.False(IsNetworkError(&RequestError{StatusCode: http.StatusNotFound}))
+}
+
+func TestThrottledRequestError(t *testing.T) {
+	assert := assert.New(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderSubStatus, "3200")
+		w.Header().Set(HeaderRetryAfterMs, "1500")
+		w.Header().Set(HeaderRequestCharge, "0.38")
+		w.Header().Set(HeaderActivityID, "throttled-activity")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"code": "429", "message": "Request rate is large"}`)
+	}))
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	_, err := client.ReadDatabase("dbs/qicAAA==")
+	assert.True(IsThrottled(err))
+	var reqErr *RequestError
+	assert.True(errors.As(err, &reqErr))
+	assert.Equal(3200, reqErr.SubStatus)
+	assert.Equal(1500*time.Millisecond, reqErr.RetryAfter)
+	assert.Equal(0.38, reqErr.RequestCharge)
+	assert.Equal("throttled-activity", reqErr.ActivityID)
+}
+
+func TestNetworkError(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(500)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	// a response that exhausted its retries is a request error
+	_, err := client.ReadDatabase("dbs/qicAAA==")
+	assert.False(IsNetworkError(err))
+	var reqErr *RequestError
+	assert.True(errors.As(err, &reqErr))
+	assert.Equal(http.StatusInternalServerError, reqErr.StatusCode)
+	assert.NotEmpty(reqErr.ActivityID)
+	assert.NotNil(reqErr.Diagnostics)
+
+	closed := ServerFactory()
+	closed.Close()
+	_, err = New(closed.URL, Config{MasterKey: "YXJpZWwNCg=="}, log).ReadDatabase("dbs/qicAAA==")
+	assert.True(IsNetworkError(err))
+	assert.False(IsTimeout(err))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	time.Sleep(time.Millisecond)
+	_, err = client.ReadDatabase("dbs/qicAAA==", WithContext(ctx))
+	assert.True(IsNetworkError(err))
+	assert.True(IsTimeout(err))
+	assert.True(errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestValidationError(t *testing.T) {
+	assert := assert.New(t)
+	client := New("url", Config{MasterKey: "YXJpZWwNCg=="}, log)
+	_, err := client.QueryDocumentsWithParameters("dbs/qicAAA==/colls/qicAAA==/", nil, &[]Document{})
+	assert.True(IsValidationError(err))
+	assert.Equal("QueryWithParameters cannot be nil", err.Error())
+
+	client = New("url", Config{MasterKey: "badkey"}, log)
+	_, err = client.ReadDatabase("dbs/qicAAA==")
+	assert.True(IsValidationError(err))
+	assert.False(IsNetworkError(err))
+}
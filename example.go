@@ -0,0 +1,161 @@
+package gocosmosdb
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// QueryByExample - Retrieves the first page of documents in a collection whose fields equal the non-zero fields of
+// the example struct and marshals them into the passed interface. To read every page pass
+// Continuation(resp.Continuation()) until it is empty, or query with QueryAllDocumentsWithParameters which follows
+// the continuations itself. Fields are named after their json tags, nested structs and maps are matched field by
+// field and every element of a slice must be contained in the document's array, struct elements are matched
+// partially on their marshaled fields so tag their fields omitempty to leave zero values out. When
+// Config.PartitionKeyStructField is set and non-zero in the example the query is scoped to that partition. Pointers
+// to zero values are matched, eg. to find documents with a false flag.
+//	filter := User{Status: "active", Address: Address{City: "Boston"}, Roles: []string{"admin"}}
+//	_, err := client.QueryByExample(coll, &filter, &users)
+func (c *CosmosDB) QueryByExample(coll string, example interface{}, docs interface{}, opts ...CallOption) (*Response, error) {
+	query, err := exampleQuery(example)
+	if err != nil {
+		return nil, err
+	}
+	if field := c.Config.PartitionKeyStructField; field != "" {
+		pk := reflect.Indirect(reflect.ValueOf(example)).FieldByName(field)
+		if pk.IsValid() && !pk.IsZero() {
+			opts = append([]CallOption{PartitionKey(pk.Interface())}, opts...)
+		}
+	}
+	return c.QueryDocumentsWithParameters(coll, query, docs, opts...)
+}
+
+// exampleQuery - returns the parameterized query matching the non-zero fields of a struct
+func exampleQuery(example interface{}) (*QueryWithParameters, error) {
+	v := reflect.ValueOf(example)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, &ValidationError{fmt.Sprintf("example must be a struct or a pointer to a struct, got %T", example)}
+	}
+	q := Select()
+	if conds := exampleConditions("c", v); len(conds) > 0 {
+		q.Where(And(conds...))
+	}
+	return q.Build(), nil
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// exampleConditions - returns the conditions matching a non-zero value at path
+func exampleConditions(path string, v reflect.Value) []Condition {
+	explicit := false
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v, explicit = v.Elem(), true
+	}
+	if !v.IsValid() || (v.IsZero() && !explicit) {
+		return nil
+	}
+	if isJSONValue(v.Type()) {
+		return []Condition{Eq(path, v.Interface())}
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return structConditions(path, v)
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return []Condition{Eq(path, v.Interface())}
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		var conds []Condition
+		for _, k := range keys {
+			conds = append(conds, exampleConditions(path+"."+k.String(), v.MapIndex(k))...)
+		}
+		return conds
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return []Condition{Eq(path, v.Interface())}
+		}
+		var conds []Condition
+		for i := 0; i < v.Len(); i++ {
+			elem := reflect.Indirect(v.Index(i))
+			switch {
+			case !elem.IsValid():
+			case !isJSONValue(elem.Type()) && (elem.Kind() == reflect.Struct || elem.Kind() == reflect.Map):
+				conds = append(conds, ArrayContainsPartial(path, v.Index(i).Interface()))
+			default:
+				conds = append(conds, ArrayContains(path, elem.Interface()))
+			}
+		}
+		return conds
+	}
+	return []Condition{Eq(path, v.Interface())}
+}
+
+// structConditions - returns the conditions matching the non-zero exported fields of a struct,
+// embedded structs without a json name are flattened like encoding/json does
+func structConditions(path string, v reflect.Value) []Condition {
+	var conds []Condition
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		name := jsonFieldName(field)
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if fv.Kind() == reflect.Ptr {
+					if fv.IsNil() {
+						continue
+					}
+					fv = fv.Elem()
+				}
+				conds = append(conds, structConditions(path, fv)...)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		conds = append(conds, exampleConditions(path+"."+name, fv)...)
+	}
+	return conds
+}
+
+// jsonFieldName - returns the name of a field in its json tag, empty when the tag has no name
+func jsonFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return tag
+	}
+	return strings.Split(tag, ",")[0]
+}
+
+// isJSONValue - whether a type marshals itself and so is compared as a whole eg. time.Time
+func isJSONValue(t reflect.Type) bool {
+	return t.Implements(marshalerType) || t.Implements(textMarshalerType) ||
+		reflect.PtrTo(t).Implements(marshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)
+}
@@ -0,0 +1,95 @@
+package gocosmosdb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type exampleAddress struct {
+	City    string `json:"city,omitempty"`
+	ZipCode string `json:"zip-code,omitempty"`
+}
+
+type exampleUser struct {
+	Document
+	Name     string            `json:"name,omitempty"`
+	Age      int               `json:"age,omitempty"`
+	Active   *bool             `json:"active,omitempty"`
+	Address  exampleAddress    `json:"address"`
+	Roles    []string          `json:"roles,omitempty"`
+	Pets     []exampleAddress  `json:"pets,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Joined   time.Time         `json:"joined"`
+	Internal string            `json:"-"`
+	Region   string
+	secret   string
+}
+
+func TestExampleQuery(t *testing.T) {
+	assert := assert.New(t)
+	active := false
+	joined := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	q, err := exampleQuery(&exampleUser{
+		Document: Document{Resource: Resource{Id: "1"}},
+		Active:   &active,
+		Address:  exampleAddress{ZipCode: "02110"},
+		Roles:    []string{"admin", "owner"},
+		Pets:     []exampleAddress{{City: "Boston"}},
+		Labels:   map[string]string{"team": "a"},
+		Joined:   joined,
+		Internal: "skipped",
+		Region:   "east",
+		secret:   "skipped",
+	})
+	assert.Nil(err)
+	assert.Equal(`SELECT * FROM c WHERE (c.id = @p0 AND c.active = @p1 AND c.address['zip-code'] = @p2 AND `+
+		`ARRAY_CONTAINS(c.roles, @p3) AND ARRAY_CONTAINS(c.roles, @p4) AND ARRAY_CONTAINS(c.pets, @p5, true) AND `+
+		`c.labels.team = @p6 AND c.joined = @p7 AND c.Region = @p8)`, q.Query)
+	assert.Equal([]QueryParameter{
+		{"@p0", "1"},
+		{"@p1", false},
+		{"@p2", "02110"},
+		{"@p3", "admin"},
+		{"@p4", "owner"},
+		{"@p5", exampleAddress{City: "Boston"}},
+		{"@p6", "a"},
+		{"@p7", joined},
+		{"@p8", "east"},
+	}, q.Parameters)
+
+	q, err = exampleQuery(exampleUser{})
+	assert.Nil(err)
+	assert.Equal("SELECT * FROM c", q.Query)
+
+	_, err = exampleQuery("nope")
+	assert.True(IsValidationError(err))
+}
+
+func TestArrayContainsPartial(t *testing.T) {
+	assert := assert.New(t)
+	q := Select().Where(ArrayContainsPartial("c.pets", exampleAddress{ZipCode: "02110"})).Build()
+	assert.Equal("SELECT * FROM c WHERE ARRAY_CONTAINS(c.pets, @p0, true)", q.Query)
+	assert.Equal([]QueryParameter{{"@p0", exampleAddress{ZipCode: "02110"}}}, q.Parameters)
+}
+
+func TestQueryByExample(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(
+		`{"_rid": "d9RzAJRFKgw=", "Documents": [{"id": "1", "name": "ann"}], "_count": 1}`,
+		`{"_rid": "d9RzAJRFKgw=", "Documents": [], "_count": 0}`,
+	)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", PartitionKeyStructField: "Name"}, log)
+	users := []exampleUser{}
+	_, err := client.QueryByExample("dbs/db/colls/coll/", &exampleUser{Name: "ann"}, &users)
+	assert.Nil(err)
+	assert.Len(users, 1)
+	assert.Equal(`["ann"]`, s.Header.Get(HeaderPartitionKey))
+	assert.JSONEq(`{"query": "SELECT * FROM c WHERE c.name = @p0", "parameters": [{"name": "@p0", "value": "ann"}]}`, s.Body)
+
+	_, err = client.QueryByExample("dbs/db/colls/coll/", &exampleUser{Age: 30}, &users)
+	assert.Nil(err)
+	assert.Equal("", s.Header.Get(HeaderPartitionKey))
+}
@@ -0,0 +1,200 @@
+package gocosmosdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// FeedResponse - the pages read by a ReadAll or QueryAll call
+type FeedResponse struct {
+	Pages         int
+	RequestCharge float64
+	Continuation  string      // the continuation of the next page when maxItems stopped the read, empty otherwise
+	Responses     []*Response // the response of every page with its diagnostics
+}
+
+// add - records the response of a page, or the request charge of a page that failed
+func (f *FeedResponse) add(resp *Response, err error) {
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		f.RequestCharge += reqErr.RequestCharge
+	}
+	if resp == nil {
+		return
+	}
+	f.Pages++
+	f.Responses = append(f.Responses, resp)
+	if charge, err := resp.GetRUs(); err == nil {
+		f.RequestCharge += charge
+	}
+}
+
+// feedPage - reads a page of a feed or query into ret
+type feedPage func(ret interface{}, opts ...CallOption) (*Response, error)
+
+// feedPages - returns the reader of the pages of a feed, a query when it is not empty
+func (c *CosmosDB) feedPages(link, query string) feedPage {
+	return func(ret interface{}, opts ...CallOption) (*Response, error) {
+		if len(query) > 0 {
+			return c.client.query(link, query, ret, opts...)
+		}
+		return c.client.read(link, ret, opts...)
+	}
+}
+
+// readAll - reads the pages of a feed following their continuation and decodes the items under key into ret.
+// Reading stops once maxItems items are read when it is positive, or when the context of the options is done.
+func (c *CosmosDB) readAll(key string, page feedPage, ret interface{}, maxItems int, opts []CallOption) (*FeedResponse, error) {
+	r := optionsRequest(opts)
+	ctx := r.context()
+	pageSize, _ := strconv.Atoi(r.Header.Get(HeaderMaxItemCount))
+	feed := &FeedResponse{}
+	items := []json.RawMessage{}
+	var continuation, sessionToken, activityID string
+	for {
+		if err := ctx.Err(); err != nil {
+			return feed, err
+		}
+		pageOpts := opts[:len(opts):len(opts)]
+		if remaining := maxItems - len(items); maxItems > 0 && (pageSize <= 0 || remaining < pageSize) {
+			pageOpts = append(pageOpts, Limit(remaining))
+		}
+		if feed.Pages > 0 {
+			// every page of the feed is part of the same logical operation
+			pageOpts = append(pageOpts, Continuation(continuation), ActivityID(activityID))
+			if sessionToken != "" {
+				pageOpts = append(pageOpts, SessionToken(sessionToken))
+			}
+		}
+		body := json.RawMessage{}
+		resp, err := page(&body, pageOpts...)
+		feed.add(resp, err)
+		if err != nil {
+			return feed, err
+		}
+		data := map[string]json.RawMessage{}
+		pageItems := []json.RawMessage{}
+		if err = json.Unmarshal(body, &data); err != nil {
+			return feed, err
+		}
+		if raw, ok := data[key]; ok {
+			if err = json.Unmarshal(raw, &pageItems); err != nil {
+				return feed, err
+			}
+		}
+		items = append(items, pageItems...)
+		if feed.Pages == 1 {
+			sessionToken, activityID = resp.SessionToken(), resp.ActivityID()
+		}
+		if continuation = resp.Continuation(); continuation == "" {
+			break
+		}
+		if maxItems > 0 && len(items) >= maxItems {
+			feed.Continuation = continuation
+			break
+		}
+	}
+	if maxItems > 0 && len(items) > maxItems {
+		items = items[:maxItems]
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return feed, err
+	}
+	return feed, c.client.decode(bytes.NewReader(data), ret)
+}
+
+// ReadAllDatabases - Retrieves every page of databases, at most maxItems when it is positive.
+//	dbs, feed, err := client.ReadAllDatabases(0, gocosmosdb.WithContext(ctx))
+func (c *CosmosDB) ReadAllDatabases(maxItems int, opts ...CallOption) ([]Database, *FeedResponse, error) {
+	return c.QueryAllDatabases("", maxItems, opts...)
+}
+
+// ReadAllCollections - Retrieves every page of collections of a database, at most maxItems when it is positive.
+//	colls, feed, err := client.ReadAllCollections("dbs/{db-id}/", 0)
+func (c *CosmosDB) ReadAllCollections(db string, maxItems int, opts ...CallOption) ([]Collection, *FeedResponse, error) {
+	return c.QueryAllCollections(db, "", maxItems, opts...)
+}
+
+// ReadAllStoredProcedures - Retrieves every page of stored procedures of a collection, at most maxItems when it is positive.
+//	sprocs, feed, err := client.ReadAllStoredProcedures("dbs/{db-id}/colls/{coll-id}/", 0)
+func (c *CosmosDB) ReadAllStoredProcedures(coll string, maxItems int, opts ...CallOption) ([]Sproc, *FeedResponse, error) {
+	return c.QueryAllStoredProcedures(coll, "", maxItems, opts...)
+}
+
+// ReadAllUserDefinedFunctions - Retrieves every page of user defined functions of a collection, at most maxItems when it is positive.
+//	udfs, feed, err := client.ReadAllUserDefinedFunctions("dbs/{db-id}/colls/{coll-id}/", 0)
+func (c *CosmosDB) ReadAllUserDefinedFunctions(coll string, maxItems int, opts ...CallOption) ([]UDF, *FeedResponse, error) {
+	return c.QueryAllUserDefinedFunctions(coll, "", maxItems, opts...)
+}
+
+// ReadAllDocuments - Retrieves every page of documents of a collection into the passed interface, at most maxItems when it is positive.
+//	feed, err := client.ReadAllDocuments("dbs/{db-id}/colls/{coll-id}/", &docs, 1000)
+func (c *CosmosDB) ReadAllDocuments(coll string, docs interface{}, maxItems int, opts ...CallOption) (*FeedResponse, error) {
+	return c.QueryAllDocuments(coll, "", docs, maxItems, opts...)
+}
+
+// QueryAllDatabases - Retrieves every page of databases that satisfy the passed query, at most maxItems when it is positive.
+//	dbs, feed, err := client.QueryAllDatabases("SELECT * FROM ROOT r", 0)
+func (c *CosmosDB) QueryAllDatabases(query string, maxItems int, opts ...CallOption) (dbs []Database, feed *FeedResponse, err error) {
+	if feed, err = c.readAll("Databases", c.feedPages("dbs", query), &dbs, maxItems, opts); err != nil {
+		dbs = nil
+	}
+	return
+}
+
+// QueryAllCollections - Retrieves every page of collections that satisfy the passed query, at most maxItems when it is positive.
+//	colls, feed, err := client.QueryAllCollections("dbs/{db-id}/", "SELECT * FROM ROOT r", 0)
+func (c *CosmosDB) QueryAllCollections(db, query string, maxItems int, opts ...CallOption) (colls []Collection, feed *FeedResponse, err error) {
+	if feed, err = c.readAll("DocumentCollections", c.feedPages(db+"colls/", query), &colls, maxItems, opts); err != nil {
+		colls = nil
+	}
+	return
+}
+
+// QueryAllStoredProcedures - Retrieves every page of stored procedures that satisfy the passed query, at most maxItems when it is positive.
+//	sprocs, feed, err := client.QueryAllStoredProcedures("dbs/{db-id}/colls/{coll-id}/", "SELECT * FROM ROOT r", 0)
+func (c *CosmosDB) QueryAllStoredProcedures(coll, query string, maxItems int, opts ...CallOption) (sprocs []Sproc, feed *FeedResponse, err error) {
+	if feed, err = c.readAll("StoredProcedures", c.feedPages(coll+"sprocs/", query), &sprocs, maxItems, opts); err != nil {
+		sprocs = nil
+	}
+	return
+}
+
+// QueryAllUserDefinedFunctions - Retrieves every page of user defined functions that satisfy the passed query, at most maxItems when it is positive.
+//	udfs, feed, err := client.QueryAllUserDefinedFunctions("dbs/{db-id}/colls/{coll-id}/", "SELECT * FROM ROOT r", 0)
+func (c *CosmosDB) QueryAllUserDefinedFunctions(coll, query string, maxItems int, opts ...CallOption) (udfs []UDF, feed *FeedResponse, err error) {
+	if feed, err = c.readAll("UserDefinedFunctions", c.feedPages(coll+"udfs/", query), &udfs, maxItems, opts); err != nil {
+		udfs = nil
+	}
+	return
+}
+
+// QueryAllPartitionKeyRanges - Retrieves every page of partition key ranges of a collection, at most maxItems when it is positive.
+//	ranges, feed, err := client.QueryAllPartitionKeyRanges("dbs/{db-id}/colls/{coll-id}/", "", 0)
+func (c *CosmosDB) QueryAllPartitionKeyRanges(coll, query string, maxItems int, opts ...CallOption) (ranges []PartitionKeyRange, feed *FeedResponse, err error) {
+	if feed, err = c.readAll("PartitionKeyRanges", c.feedPages(coll+"pkranges/", query), &ranges, maxItems, opts); err != nil {
+		ranges = nil
+	}
+	return
+}
+
+// QueryAllDocuments - Retrieves every page of documents that satisfy the passed query into the passed interface, at most maxItems when it is positive.
+//	feed, err := client.QueryAllDocuments(coll, "SELECT * FROM ROOT r", &docs, 0)
+func (c *CosmosDB) QueryAllDocuments(coll, query string, docs interface{}, maxItems int, opts ...CallOption) (*FeedResponse, error) {
+	return c.readAll("Documents", c.feedPages(coll+"docs/", query), docs, maxItems, opts)
+}
+
+// QueryAllDocumentsWithParameters - Retrieves every page of documents that satisfy a query with parameters into the passed interface, at most maxItems when it is positive.
+//	feed, err := client.QueryAllDocumentsWithParameters(coll, queryWithParams, &docs, 0)
+func (c *CosmosDB) QueryAllDocumentsWithParameters(coll string, query *QueryWithParameters, docs interface{}, maxItems int, opts ...CallOption) (*FeedResponse, error) {
+	if query == nil {
+		return nil, &ValidationError{"QueryWithParameters cannot be nil"}
+	}
+	page := func(ret interface{}, opts ...CallOption) (*Response, error) {
+		return c.client.queryWithParameters(coll+"docs/", query, ret, opts...)
+	}
+	return c.readAll("Documents", page, docs, maxItems, opts)
+}
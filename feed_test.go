@@ -0,0 +1,122 @@
+package gocosmosdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// itemPages - returns the pages of n items under key, size items per page
+func itemPages(key string, n, size int) []string {
+	pages := []string{}
+	for start := 0; start < n; start += size {
+		items := []string{}
+		for i := start; i < start+size && i < n; i++ {
+			items = append(items, fmt.Sprintf(`{"id": "%d"}`, i))
+		}
+		pages = append(pages, fmt.Sprintf(`{"%s": [%s], "_count": %d}`, key, strings.Join(items, ","), len(items)))
+	}
+	return pages
+}
+
+func TestReadAllDatabases(t *testing.T) {
+	assert := assert.New(t)
+	s, requests := pagedServer(itemPages("Databases", 5, 2)...)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	dbs, feed, err := client.ReadAllDatabases(0)
+	assert.Nil(err)
+	assert.Len(dbs, 5)
+	assert.Equal("4", dbs[4].Id)
+	assert.Equal(3, feed.Pages)
+	assert.Equal(7.5, feed.RequestCharge)
+	assert.Equal("", feed.Continuation)
+	assert.Len(feed.Responses, 3)
+	assert.Equal("2", (*requests)[2].Header.Get(HeaderContinuation))
+	assert.Equal("0:1", (*requests)[2].Header.Get(HeaderSessionToken))
+	assert.Equal((*requests)[0].Header.Get(HeaderActivityID), (*requests)[2].Header.Get(HeaderActivityID))
+}
+
+func TestQueryAllDocumentsMaxItems(t *testing.T) {
+	assert := assert.New(t)
+	s, requests := pagedServer(itemPages("Documents", 10, 2)...)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	docs := []Document{}
+	feed, err := client.QueryAllDocuments("dbs/db/colls/coll/", "SELECT * FROM c", &docs, 5, Limit(2))
+	assert.Nil(err)
+	assert.Len(docs, 5)
+	assert.Equal(3, feed.Pages)
+	assert.Equal("3", feed.Continuation)
+	assert.Equal("1", (*requests)[2].Header.Get(HeaderMaxItemCount))
+
+	docs = []Document{}
+	feed, err = client.QueryAllDocumentsWithParameters("dbs/db/colls/coll/", Select().Build(), &docs, 0, Limit(4))
+	assert.Nil(err)
+	assert.Len(docs, 10)
+	assert.Equal(5, feed.Pages)
+
+	_, err = client.QueryAllDocumentsWithParameters("dbs/db/colls/coll/", nil, &docs, 0)
+	assert.True(IsValidationError(err))
+}
+
+func TestReadAllFeeds(t *testing.T) {
+	assert := assert.New(t)
+	for key, read := range map[string]func(c *CosmosDB) (int, error){
+		"DocumentCollections": func(c *CosmosDB) (int, error) {
+			colls, _, err := c.ReadAllCollections("dbs/db/", 0)
+			return len(colls), err
+		},
+		"StoredProcedures": func(c *CosmosDB) (int, error) {
+			sprocs, _, err := c.ReadAllStoredProcedures("dbs/db/colls/coll/", 0)
+			return len(sprocs), err
+		},
+		"UserDefinedFunctions": func(c *CosmosDB) (int, error) {
+			udfs, _, err := c.ReadAllUserDefinedFunctions("dbs/db/colls/coll/", 0)
+			return len(udfs), err
+		},
+		"PartitionKeyRanges": func(c *CosmosDB) (int, error) {
+			ranges, _, err := c.QueryAllPartitionKeyRanges("dbs/db/colls/coll/", "", 0)
+			return len(ranges), err
+		},
+		"Documents": func(c *CosmosDB) (int, error) {
+			docs := []Document{}
+			_, err := c.ReadAllDocuments("dbs/db/colls/coll/", &docs, 0)
+			return len(docs), err
+		},
+	} {
+		s, _ := pagedServer(itemPages(key, 3, 2)...)
+		n, err := read(New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log))
+		s.Close()
+		assert.Nil(err, key)
+		assert.Equal(3, n, key)
+	}
+}
+
+func TestReadAllErrors(t *testing.T) {
+	assert := assert.New(t)
+	s, requests := pagedServer(append(itemPages("StoredProcedures", 2, 2), "400")...)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	sprocs, feed, err := client.QueryAllStoredProcedures("dbs/db/colls/coll/", "SELECT * FROM c", 0)
+	var reqErr *RequestError
+	assert.True(errors.As(err, &reqErr))
+	assert.Equal(http.StatusBadRequest, reqErr.StatusCode)
+	assert.Nil(sprocs)
+	assert.Equal(1, feed.Pages)
+	// the failed page is charged too
+	assert.Equal(5.0, feed.RequestCharge)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	*requests = nil
+	_, feed, err = client.ReadAllStoredProcedures("dbs/db/colls/coll/", 0, WithContext(ctx))
+	assert.Equal(context.Canceled, err)
+	assert.Equal(0, feed.Pages)
+	assert.Len(*requests, 0)
+}
@@ -0,0 +1,358 @@
+package gocosmosdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Recorder - receives a capture of every request and response, set it as Config.Recorder to debug
+// the traffic of a client. Authorization signatures and Config.RedactFields are redacted from captures.
+type Recorder interface {
+	Record(entry *HAREntry) error
+}
+
+// HAR - an HTTP Archive 1.2 document, it can be opened in browser developer tools and HAR viewers
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog - the log of an HTTP Archive
+type HARLog struct {
+	Version string      `json:"version"`
+	Creator HARCreator  `json:"creator"`
+	Entries []*HAREntry `json:"entries"`
+}
+
+// HARCreator - the application that created an HTTP Archive
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry - a captured request and its response, the underscored fields are Cosmos DB specific
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	Operation       string      `json:"_operation,omitempty"`
+	ActivityID      string      `json:"_activityId,omitempty"`
+	RequestCharge   float64     `json:"_requestCharge,omitempty"`
+	Retries         int         `json:"_retries"`
+	RetryReasons    []string    `json:"_retryReasons,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+// HARRequest - a captured request
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse - a captured response
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue - a header, cookie or query string parameter
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData - the body of a captured request
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent - the body of a captured response
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+}
+
+// HARTimings - the time in milliseconds spent waiting for the response, with retries, and reading its body
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// HARRecorder - keeps captured entries in memory to write them as an HTTP Archive
+//	recorder := gocosmosdb.NewHARRecorder()
+//	client := gocosmosdb.New(url, gocosmosdb.Config{MasterKey: key, Recorder: recorder}, log)
+//	...
+//	err := recorder.WriteFile("session.har")
+type HARRecorder struct {
+	mu      sync.Mutex
+	entries []*HAREntry
+}
+
+// NewHARRecorder - creates an empty HAR recorder
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{}
+}
+
+// Record - adds an entry to the archive
+func (h *HARRecorder) Record(entry *HAREntry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, entry)
+	return nil
+}
+
+// HAR - returns the archive of the entries recorded so far
+func (h *HARRecorder) HAR() *HAR {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return &HAR{HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "gocosmosdb", Version: UserAgent},
+		Entries: append([]*HAREntry{}, h.entries...),
+	}}
+}
+
+// WriteTo - writes the archive as JSON
+func (h *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	data, err := json.MarshalIndent(h.HAR(), "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// WriteFile - writes the archive to a file
+func (h *HARRecorder) WriteFile(path string) error {
+	var buf bytes.Buffer
+	if _, err := h.WriteTo(&buf); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf.Bytes(), 0600)
+}
+
+// NDJSONRecorder - appends every captured entry as a line of JSON to a log file, rotating the file
+// once it grows past a maximum size
+type NDJSONRecorder struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewNDJSONRecorder - opens or creates the log file at path. When maxSize is greater than 0 the file is
+// rotated to path.1, path.2, ... before it grows past maxSize bytes, keeping at most maxBackups old files.
+func NewNDJSONRecorder(path string, maxSize int64, maxBackups int) (*NDJSONRecorder, error) {
+	n := &NDJSONRecorder{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := n.open(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// open - opens the log file for appending
+func (n *NDJSONRecorder) open() error {
+	file, err := os.OpenFile(n.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	n.file, n.size = file, info.Size()
+	return nil
+}
+
+// Record - appends an entry to the log file
+func (n *NDJSONRecorder) Record(entry *HAREntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.file == nil {
+		return os.ErrClosed
+	}
+	if n.maxSize > 0 && n.size > 0 && n.size+int64(len(line)) > n.maxSize {
+		if err := n.rotate(); err != nil {
+			return err
+		}
+	}
+	written, err := n.file.Write(line)
+	n.size += int64(written)
+	return err
+}
+
+// rotate - shifts the old log files by one, dropping the oldest, and starts a new log file
+func (n *NDJSONRecorder) rotate() error {
+	if err := n.file.Close(); err != nil {
+		return err
+	}
+	n.file = nil
+	if n.maxBackups > 0 {
+		for i := n.maxBackups - 1; i > 0; i-- {
+			os.Rename(n.backup(i), n.backup(i+1))
+		}
+		if err := os.Rename(n.path, n.backup(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(n.path); err != nil {
+		return err
+	}
+	return n.open()
+}
+
+// backup - returns the path of the i-th old log file
+func (n *NDJSONRecorder) backup(i int) string {
+	return n.path + "." + strconv.Itoa(i)
+}
+
+// Close - closes the log file
+func (n *NDJSONRecorder) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.file == nil {
+		return nil
+	}
+	err := n.file.Close()
+	n.file = nil
+	return err
+}
+
+// recordingInterceptor - captures every request and its response and passes them to the recorder,
+// the response body is buffered so it can be captured
+func recordingInterceptor(recorder Recorder, rd *redactor, log Logger) Interceptor {
+	return func(r *Request, next Invoker) (*http.Response, error) {
+		body, err := readBody(r.Request)
+		if err != nil {
+			return nil, err
+		}
+		entry := &HAREntry{
+			StartedDateTime: time.Now(),
+			Request:         harRequest(r.Request, rd, body),
+			Operation:       r.Operation(),
+		}
+		resp, err := next(r)
+		wait := time.Since(entry.StartedDateTime)
+		if err != nil {
+			entry.Error = err.Error()
+			entry.Response = HARResponse{Cookies: []HARNameValue{}, Headers: []HARNameValue{}, HeadersSize: -1, BodySize: -1}
+		} else {
+			var respBody []byte
+			respBody, err = ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+			entry.Response = harResponse(resp, rd, respBody)
+			entry.ActivityID = resp.Header.Get(HeaderActivityID)
+			entry.RequestCharge, _ = strconv.ParseFloat(resp.Header.Get(HeaderRequestCharge), 64)
+		}
+		if d := diagnosticsFromContext(r.rContext); d != nil {
+			entry.Retries, entry.RetryReasons = d.Retries, append([]string{}, d.RetryReasons...)
+		}
+		total := time.Since(entry.StartedDateTime)
+		entry.Time = milliseconds(total)
+		entry.Timings = HARTimings{Wait: milliseconds(wait), Receive: milliseconds(total - wait)}
+		if recErr := recorder.Record(entry); recErr != nil && log != nil {
+			log.Warningf("CosmosDB Recorder: %s", recErr)
+		}
+		return resp, err
+	}
+}
+
+// harRequest - captures a request with its authorization and redacted fields removed
+func harRequest(r *http.Request, rd *redactor, body []byte) HARRequest {
+	req := HARRequest{
+		Method:      r.Method,
+		URL:         r.URL.String(),
+		HTTPVersion: r.Proto,
+		Cookies:     []HARNameValue{},
+		Headers:     harHeaders(rd.header(r.Header)),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+	if req.HTTPVersion == "" {
+		req.HTTPVersion = "HTTP/1.1"
+	}
+	for name, values := range r.URL.Query() {
+		for _, v := range values {
+			req.QueryString = append(req.QueryString, HARNameValue{name, v})
+		}
+	}
+	if len(body) > 0 {
+		req.PostData = &HARPostData{MimeType: r.Header.Get(HeaderContentType), Text: string(rd.body(body))}
+	}
+	return req
+}
+
+// harResponse - captures a response with its redacted fields removed
+func harResponse(resp *http.Response, rd *redactor, body []byte) HARResponse {
+	return HARResponse{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HTTPVersion: fmt.Sprintf("HTTP/%d.%d", resp.ProtoMajor, resp.ProtoMinor),
+		Cookies:     []HARNameValue{},
+		Headers:     harHeaders(resp.Header),
+		Content: HARContent{
+			Size:     len(body),
+			MimeType: resp.Header.Get(HeaderContentType),
+			Text:     string(rd.body(body)),
+		},
+		HeadersSize: -1,
+		BodySize:    len(body),
+	}
+}
+
+// harHeaders - returns the headers sorted by name
+func harHeaders(h http.Header) []HARNameValue {
+	headers := []HARNameValue{}
+	for name, values := range h {
+		for _, v := range values {
+			headers = append(headers, HARNameValue{name, v})
+		}
+	}
+	sort.SliceStable(headers, func(i, j int) bool { return headers[i].Name < headers[j].Name })
+	return headers
+}
+
+// milliseconds - converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
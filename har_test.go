@@ -0,0 +1,121 @@
+package gocosmosdb
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHARRecorder(t *testing.T) {
+	assert := assert.New(t)
+	attempts := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set(HeaderRequestCharge, "5.5")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id": "1", "secret": "s3cr3t"}`)
+	}))
+	defer s.Close()
+	recorder := NewHARRecorder()
+	client := New(s.URL, Config{
+		MasterKey:    "YXJpZWwNCg==",
+		RetryMax:     1,
+		RetryWaitMax: time.Millisecond,
+		Recorder:     recorder,
+		RedactFields: []string{"secret"},
+	}, log)
+	doc := struct {
+		Document
+		Secret string `json:"secret"`
+	}{Document: Document{Resource: Resource{Id: "1"}}, Secret: "s3cr3t"}
+	_, err := client.CreateDocument("dbs/db/colls/coll/", &doc)
+	assert.Nil(err)
+	assert.Equal("s3cr3t", doc.Secret)
+
+	har := recorder.HAR()
+	assert.Equal("1.2", har.Log.Version)
+	assert.Len(har.Log.Entries, 1)
+	entry := har.Log.Entries[0]
+	assert.Equal("CreateDocument", entry.Operation)
+	assert.Equal(1, entry.Retries)
+	assert.Equal([]string{"503"}, entry.RetryReasons)
+	assert.Equal(5.5, entry.RequestCharge)
+	assert.Equal("POST", entry.Request.Method)
+	assert.JSONEq(`{"id": "1", "secret": "REDACTED"}`, entry.Request.PostData.Text)
+	assert.Equal(http.StatusCreated, entry.Response.Status)
+	assert.JSONEq(`{"id": "1", "secret": "REDACTED"}`, entry.Response.Content.Text)
+	for _, h := range entry.Request.Headers {
+		if h.Name == HeaderAuth {
+			assert.Contains(h.Value, "sig%3DREDACTED")
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "session.har")
+	assert.Nil(recorder.WriteFile(path))
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(err)
+	assert.NotContains(string(data), "s3cr3t")
+	decoded := HAR{}
+	assert.Nil(json.Unmarshal(data, &decoded))
+	assert.Len(decoded.Log.Entries, 1)
+}
+
+func TestHARRecorderTransportError(t *testing.T) {
+	assert := assert.New(t)
+	recorder := NewHARRecorder()
+	client := New("http://localhost:1", Config{MasterKey: "YXJpZWwNCg==", Recorder: recorder}, log)
+	_, err := client.ReadDatabase("dbs/db")
+	assert.NotNil(err)
+	entries := recorder.HAR().Log.Entries
+	assert.Len(entries, 1)
+	assert.NotEmpty(entries[0].Error)
+}
+
+func TestNDJSONRecorderRotates(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "traffic.ndjson")
+	recorder, err := NewNDJSONRecorder(path, 200, 2)
+	assert.Nil(err)
+	defer recorder.Close()
+	for i := 0; i < 5; i++ {
+		assert.Nil(recorder.Record(&HAREntry{Operation: fmt.Sprintf("op%d", i), Request: HARRequest{URL: strings.Repeat("x", 50)}}))
+	}
+	assert.Nil(recorder.Close())
+
+	// every entry fits alone in a file, the oldest rotated files are dropped
+	lines := func(p string) []string {
+		f, err := os.Open(p)
+		if err != nil {
+			return nil
+		}
+		defer f.Close()
+		var ops []string
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			entry := HAREntry{}
+			assert.Nil(json.Unmarshal(scanner.Bytes(), &entry))
+			ops = append(ops, entry.Operation)
+		}
+		return ops
+	}
+	assert.Equal([]string{"op4"}, lines(path))
+	assert.Equal([]string{"op3"}, lines(path+".1"))
+	assert.Equal([]string{"op2"}, lines(path+".2"))
+	_, err = os.Stat(path + ".3")
+	assert.True(os.IsNotExist(err))
+	assert.Equal(os.ErrClosed, recorder.Record(&HAREntry{}))
+}
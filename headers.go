@@ -95,9 +95,15 @@ const (
 	// HeaderRequestCharge - The number of request units consumed by the operation.
 	HeaderRequestCharge = "X-Ms-Request-Charge"
 
+	// HeaderRetryAfterMs - The number of milliseconds to wait before retrying a throttled request.
+	HeaderRetryAfterMs = "X-Ms-Retry-After-Ms"
+
 	// HeaderSessionToken - A string token used with session level consistency.
 	HeaderSessionToken = "X-Ms-Session-Token"
 
+	// HeaderSubStatus - The substatus code of the response, which further classifies its status code.
+	HeaderSubStatus = "X-Ms-Substatus"
+
 	// HeaderSupportedQueryFeatures -
 	HeaderSupportedQueryFeatures = "X-Ms-Cosmos-Supported-Query-Features"
 
@@ -0,0 +1,115 @@
+package gocosmosdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/moul/http2curl"
+)
+
+// Invoker - sends a request and returns the raw http response
+type Invoker func(r *Request) (*http.Response, error)
+
+// Interceptor - wraps the sending of every request. An interceptor can inspect or change the request,
+// call next to send it, and inspect, wrap or replace the response and error. Not calling next
+// short-circuits the request. The response body is read by the client after the chain returns.
+//	audit := func(r *gocosmosdb.Request, next gocosmosdb.Invoker) (*http.Response, error) {
+//		resp, err := next(r)
+//		log.Infof("%s %s: %v", r.Method, r.URL, err)
+//		return resp, err
+//	}
+type Interceptor func(r *Request, next Invoker) (*http.Response, error)
+
+// chainInterceptors - returns an invoker running the interceptors in order around invoker
+func chainInterceptors(invoker Invoker, interceptors ...Interceptor) Invoker {
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], invoker
+		if interceptor == nil {
+			continue
+		}
+		invoker = func(r *Request) (*http.Response, error) {
+			return interceptor(r, next)
+		}
+	}
+	return invoker
+}
+
+// use - appends interceptors to the client's chain
+func (c *apiClient) use(interceptors ...Interceptor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.config.Interceptors = append(c.config.Interceptors[:len(c.config.Interceptors):len(c.config.Interceptors)], interceptors...)
+}
+
+// chain - returns the invoker for a request, the configured interceptors followed by the built-in ones
+func (c *apiClient) chain() Invoker {
+	c.mu.RLock()
+	interceptors := c.config.Interceptors
+	debug, verbose := c.config.Debug, c.config.Verbose
+	redact := c.config.RedactFields
+	recorder := c.config.Recorder
+	metrics := c.config.Metrics
+	tracer := c.config.Tracer
+	slowQueries := c.config.SlowQueryLog
+	c.mu.RUnlock()
+	if tracer != nil {
+		if _, noop := tracer.(NoopTracer); !noop {
+			interceptors = append(interceptors[:len(interceptors):len(interceptors)], tracingInterceptor(tracer))
+		}
+	}
+	if metrics != nil {
+		interceptors = append(interceptors[:len(interceptors):len(interceptors)], metrics.Interceptor())
+	}
+	if slowQueries != nil {
+		interceptors = append(interceptors[:len(interceptors):len(interceptors)], slowQueries.interceptor(c.logger))
+	}
+	if recorder != nil {
+		interceptors = append(interceptors[:len(interceptors):len(interceptors)], recordingInterceptor(recorder, newRedactor(redact), c.logger))
+	}
+	if debug && c.logger != nil {
+		interceptors = append(interceptors[:len(interceptors):len(interceptors)], debugInterceptor(c.logger, verbose, newRedactor(redact)))
+	}
+	return chainInterceptors(c.invoke, interceptors...)
+}
+
+// debugInterceptor - logs requests as curl commands and, when verbose, dumps responses, secrets and the
+// redacted document fields are removed from everything logged
+func debugInterceptor(log Logger, verbose bool, rd *redactor) Interceptor {
+	return func(r *Request, next Invoker) (*http.Response, error) {
+		r.QueryMetricsHeaders()
+		safe, err := rd.request(r.Request)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("CosmosDB Request: ID: %+v, Type: %+v, HTTP Request: %+v", r.rId, r.rType, safe)
+		curl, _ := http2curl.GetCurlCommand(safe)
+		log.Infof("CURL: %s", curl)
+		resp, err := next(r)
+		if err != nil || !verbose {
+			return resp, err
+		}
+		if resp.Request != nil {
+			log.Infof("CosmosDB Request: %s", spew.Sdump(redactedRequest(resp.Request, rd)))
+		}
+		log.Infof("CosmosDB Response Headers: %s", spew.Sdump(resp.Header))
+		log.Infof("CosmosDB Response Content-Length: %s", spew.Sdump(resp.ContentLength))
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("CosmosDB Response Content: %s", rd.body(body))
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return resp, nil
+	}
+}
+
+// redactedRequest - returns a copy of a sent request, without its body, safe to dump
+func redactedRequest(r *http.Request, rd *redactor) *http.Request {
+	clone := r.Clone(r.Context())
+	clone.Header = rd.header(r.Header)
+	clone.Body, clone.GetBody = nil, nil
+	return clone
+}
@@ -0,0 +1,118 @@
+package gocosmosdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterceptorChain(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(`{"id": "iot2"}`)
+	defer s.Close()
+	calls := []string{}
+	record := func(name string) Interceptor {
+		return func(r *Request, next Invoker) (*http.Response, error) {
+			calls = append(calls, name+" before")
+			r.Header.Set("X-Test-"+name, "true")
+			resp, err := next(r)
+			calls = append(calls, name+" after")
+			return resp, err
+		}
+	}
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", Interceptors: []Interceptor{record("first"), nil}}, log)
+	client.Use(record("second"))
+	db, err := client.ReadDatabase("dbs/qicAAA==")
+	assert.Nil(err)
+	assert.Equal("iot2", db.Id)
+	assert.Equal([]string{"first before", "second before", "second after", "first after"}, calls)
+	s.AssertHeaders(t, "X-Test-First", "X-Test-Second")
+	assert.Len(client.GetConfig().Interceptors, 3)
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	assert := assert.New(t)
+	client := New("http://localhost:1", Config{MasterKey: "YXJpZWwNCg=="}, log)
+
+	// return a canned response without sending the request
+	client.Use(func(r *Request, next Invoker) (*http.Response, error) {
+		if r.Method == http.MethodGet {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id": "cached"}`)),
+			}, nil
+		}
+		return nil, errors.New("chaos")
+	})
+	db, err := client.ReadDatabase("dbs/qicAAA==")
+	assert.Nil(err)
+	assert.Equal("cached", db.Id)
+	_, err = client.DeleteDatabase("dbs/qicAAA==")
+	assert.Equal("chaos", err.Error())
+
+	// a missing response is an error
+	client = New("http://localhost:1", Config{MasterKey: "YXJpZWwNCg=="}, log)
+	client.Use(func(r *Request, next Invoker) (*http.Response, error) {
+		if r.Method == http.MethodGet {
+			return nil, nil
+		}
+		return &http.Response{StatusCode: http.StatusNoContent}, nil
+	})
+	_, err = client.ReadDatabase("dbs/qicAAA==")
+	assert.Equal("no response for GET http://localhost:1/dbs/qicAAA==", err.Error())
+	_, err = client.DeleteDatabase("dbs/qicAAA==")
+	assert.NotNil(err)
+}
+
+func TestDebugInterceptorKeepsBody(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(`{"id": "iot2"}`)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", Debug: true, Verbose: true}, log)
+	db, err := client.ReadDatabase("dbs/qicAAA==")
+	assert.Nil(err)
+	assert.Equal("iot2", db.Id)
+	s.AssertHeaders(t, HeaderPopulateQueryMetrics)
+}
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (l *recordingLogger) Infof(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+func (l *recordingLogger) Warningf(format string, v ...interface{}) { l.Infof(format, v...) }
+func (l *recordingLogger) Errorf(format string, v ...interface{})   { l.Infof(format, v...) }
+
+func TestDebugInterceptorRedacts(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(`{"id": "1", "ssn": "123-45", "_token": "resource-token"}`)
+	defer s.Close()
+	s.SetStatus(http.StatusCreated)
+	logs := &recordingLogger{}
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", Debug: true, Verbose: true, RedactFields: []string{"ssn"}}, logs)
+	doc := struct {
+		Document
+		SSN   string `json:"ssn"`
+		Token string `json:"_token"`
+	}{Document: Document{Resource: Resource{Id: "1"}}, SSN: "123-45"}
+	_, err := client.CreateDocument("dbs/db/colls/coll/", &doc)
+	assert.Nil(err)
+	assert.Equal("resource-token", doc.Token)
+	auth := s.Header.Get(HeaderAuth)
+	sig := auth[strings.Index(auth, "sig%3D")+len("sig%3D"):]
+	output := strings.Join(logs.lines, "\n")
+	assert.Contains(output, "curl")
+	assert.Contains(output, Redacted)
+	assert.NotContains(output, sig)
+	assert.NotContains(output, "123-45")
+	assert.NotContains(output, "resource-token")
+}
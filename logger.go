@@ -0,0 +1,9 @@
+package gocosmosdb
+
+// Logger - the leveled logger the client writes its debug output to. A *logger.Logger from
+// github.com/intwinelabs/logger satisfies it, use NewSlogLogger to log to a *slog.Logger.
+type Logger interface {
+	Infof(format string, v ...interface{})
+	Warningf(format string, v ...interface{})
+	Errorf(format string, v ...interface{})
+}
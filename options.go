@@ -3,6 +3,7 @@ package gocosmosdb
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 	"strconv"
 )
 
@@ -99,7 +100,8 @@ func SessionToken(sessionToken string) CallOption {
 	}
 }
 
-// CrossPartition - allows query to run on all partitions
+// CrossPartition - allows query to run on all partitions, the service does not merge the results of the partitions,
+// use NewCrossPartitionQuery for queries that need it eg. ORDER BY
 func CrossPartition() CallOption {
 	return func(r *Request) error {
 		r.Header.Set(HeaderCrossPartition, "true")
@@ -166,7 +168,8 @@ func EnableQueryScan() CallOption {
 	}
 }
 
-// EnableParallelizeCrossPartitionQuery - add the parallelize header
+// EnableParallelizeCrossPartitionQuery - add the parallelize header, CrossPartitionQuery.SetMaxDegreeOfParallelism
+// reads the partition key ranges concurrently on the client
 func EnableParallelizeCrossPartitionQuery() CallOption {
 	return func(r *Request) error {
 		r.Header.Set(HeaderParalelizeCrossPartition, "true")
@@ -174,7 +177,8 @@ func EnableParallelizeCrossPartitionQuery() CallOption {
 	}
 }
 
-// EnablePopulateQueryMetrics - add the parallelize header
+// EnablePopulateQueryMetrics - add the populate query metrics header, read them with Response.GetQueryMetrics or
+// set Config.SlowQueryLog to collect them for every query
 func EnablePopulateQueryMetrics() CallOption {
 	return func(r *Request) error {
 		r.Header.Set(HeaderPopulateQueryMetrics, "true")
@@ -190,6 +194,26 @@ func WithContext(ctx context.Context) CallOption {
 	}
 }
 
+// optionsRequest - returns an empty request with the options applied, to read the context or headers they set
+func optionsRequest(opts []CallOption) *Request {
+	r := &Request{Request: &http.Request{Header: http.Header{}}}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(r)
+		}
+	}
+	return r
+}
+
+// ActivityID - sets the client supplied identifier of the logical operation, echoed in the server response.
+// When not set the activity ID of the request's context is used, or a new one is generated.
+func ActivityID(id string) CallOption {
+	return func(r *Request) error {
+		r.Header.Set(HeaderActivityID, id)
+		return nil
+	}
+}
+
 // QueryVersion - add the query latest query version header
 func QueryVersion() CallOption {
 	return func(r *Request) error {
@@ -30,6 +30,7 @@ func TestCallOptions(t *testing.T) {
 	ctx := context.WithValue(context.Background(), "foo", "bar")
 	opts = append(opts, WithContext(ctx))
 	opts = append(opts, QueryVersion())
+	opts = append(opts, ActivityID("activityID"))
 
 	link := "http://localhost:8080"
 	req, err := http.NewRequest("POST", link, nil)
@@ -61,4 +62,5 @@ func TestCallOptions(t *testing.T) {
 	assert.Equal("true", r.Header.Get(HeaderPopulateQueryMetrics))
 	assert.Equal(ctx, r.rContext)
 	assert.Equal("1.4", r.Header.Get(HeaderQueryVersion))
+	assert.Equal("activityID", r.Header.Get(HeaderActivityID))
 }
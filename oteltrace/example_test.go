@@ -0,0 +1,61 @@
+package oteltrace_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/intwinelabs/gocosmosdb/oteltrace"
+)
+
+// vendorSpan - stands in for an OpenTelemetry trace.Span, whose methods take variadic options
+type vendorSpan struct {
+	name string
+}
+
+func (s *vendorSpan) SetAttributes(key, value string, options ...interface{}) {
+	fmt.Printf("%s: %s=%s\n", s.name, key, value)
+}
+
+func (s *vendorSpan) SetStatus(code uint32, description string) {
+	fmt.Printf("%s: status %d %s\n", s.name, code, description)
+}
+
+func (s *vendorSpan) RecordError(err error, options ...interface{}) {
+	fmt.Printf("%s: error %v\n", s.name, err)
+}
+
+func (s *vendorSpan) End(options ...interface{}) {
+	fmt.Printf("%s: end\n", s.name)
+}
+
+type otelTracer struct{}
+
+func (t otelTracer) Start(ctx context.Context, name string) (context.Context, oteltrace.Span) {
+	return ctx, otelSpan{&vendorSpan{name}}
+}
+
+type otelSpan struct{ *vendorSpan }
+
+func (s otelSpan) SetAttribute(key string, value interface{}) {
+	s.SetAttributes(key, fmt.Sprint(value))
+}
+
+func (s otelSpan) SetStatus(code oteltrace.StatusCode, description string) {
+	s.vendorSpan.SetStatus(uint32(code), description)
+}
+
+func (s otelSpan) RecordError(err error) { s.vendorSpan.RecordError(err) }
+
+func (s otelSpan) End() { s.vendorSpan.End() }
+
+func Example() {
+	tracer := oteltrace.New(otelTracer{})
+	_, span := tracer.Start(context.Background(), "ReadDocument")
+	span.End(errors.New("unavailable"))
+	// Output:
+	// ReadDocument: db.system=cosmosdb
+	// ReadDocument: error unavailable
+	// ReadDocument: status 1 unavailable
+	// ReadDocument: end
+}
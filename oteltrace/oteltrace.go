@@ -0,0 +1,136 @@
+// Package oteltrace adapts an OpenTelemetry style tracer to the gocosmosdb Tracer interface, translating the
+// attributes set by the client to the OpenTelemetry database semantic conventions and setting the span status.
+//
+// The package does not import OpenTelemetry, wrap a trace.Tracer in a few lines to use it:
+//
+//	type otelTracer struct{ tracer trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, oteltrace.Span) {
+//		ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//		return ctx, otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) SetAttribute(key string, value interface{}) {
+//		s.SetAttributes(attribute.String(key, fmt.Sprint(value)))
+//	}
+//
+//	func (s otelSpan) SetStatus(code oteltrace.StatusCode, description string) {
+//		s.Span.SetStatus(codes.Code(code), description)
+//	}
+//
+//	func (s otelSpan) RecordError(err error) { s.Span.RecordError(err) }
+//
+//	func (s otelSpan) End() { s.Span.End() }
+//
+// The methods of trace.Span take variadic options, every method of Span has to be declared on the wrapper.
+//
+//	client := gocosmosdb.New(url, gocosmosdb.Config{Tracer: oteltrace.New(otelTracer{otel.Tracer("cosmosdb")})}, log)
+package oteltrace
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/intwinelabs/gocosmosdb"
+)
+
+// StatusCode - the status of a span, the values match the OpenTelemetry codes
+type StatusCode uint32
+
+const (
+	Unset StatusCode = 0
+	Error StatusCode = 1
+	Ok    StatusCode = 2
+)
+
+// Attribute keys of the OpenTelemetry semantic conventions
+const (
+	AttrDBSystem      = "db.system"
+	AttrDBName        = "db.name"
+	AttrDBOperation   = "db.operation"
+	AttrContainer     = "db.cosmosdb.container"
+	AttrStatusCode    = "db.cosmosdb.status_code"
+	AttrSubStatusCode = "db.cosmosdb.sub_status_code"
+	AttrRequestCharge = "db.cosmosdb.request_charge"
+	AttrActivityID    = "db.cosmosdb.activity_id"
+	AttrMethod        = "http.request.method"
+	AttrResendCount   = "http.request.resend_count"
+)
+
+// attributes - the semantic convention key of every attribute set by the client
+var attributes = map[string]string{
+	gocosmosdb.TraceAttrDatabase:      AttrDBName,
+	gocosmosdb.TraceAttrCollection:    AttrContainer,
+	gocosmosdb.TraceAttrOperation:     AttrDBOperation,
+	gocosmosdb.TraceAttrActivityID:    AttrActivityID,
+	gocosmosdb.TraceAttrStatusCode:    AttrStatusCode,
+	gocosmosdb.TraceAttrSubStatus:     AttrSubStatusCode,
+	gocosmosdb.TraceAttrRequestCharge: AttrRequestCharge,
+	gocosmosdb.TraceAttrMethod:        AttrMethod,
+}
+
+// Tracer - the shape of an OpenTelemetry tracer
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span - the shape of an OpenTelemetry span
+type Span interface {
+	SetAttribute(key string, value interface{})
+	SetStatus(code StatusCode, description string)
+	RecordError(err error)
+	End()
+}
+
+// New - returns a gocosmosdb Tracer starting its spans with the passed tracer
+func New(tracer Tracer) gocosmosdb.Tracer {
+	return &adapter{tracer}
+}
+
+type adapter struct {
+	tracer Tracer
+}
+
+// Start - starts a span with the db.system attribute set
+func (a *adapter) Start(ctx context.Context, name string) (context.Context, gocosmosdb.Span) {
+	ctx, span := a.tracer.Start(ctx, name)
+	span.SetAttribute(AttrDBSystem, "cosmosdb")
+	return ctx, &spanAdapter{span: span}
+}
+
+type spanAdapter struct {
+	span   Span
+	status int
+}
+
+// SetAttribute - sets an attribute under its semantic convention key
+func (s *spanAdapter) SetAttribute(key string, value interface{}) {
+	switch key {
+	case gocosmosdb.TraceAttrStatusCode:
+		s.status, _ = value.(int)
+	case gocosmosdb.TraceAttrAttempt:
+		// the first attempt is not a resend
+		if attempt, ok := value.(int); ok {
+			s.span.SetAttribute(AttrResendCount, attempt-1)
+		}
+		return
+	}
+	if k, ok := attributes[key]; ok {
+		key = k
+	}
+	s.span.SetAttribute(key, value)
+}
+
+// End - sets the span status from the error or the response status and ends the span
+func (s *spanAdapter) End(err error) {
+	switch {
+	case err != nil:
+		s.span.RecordError(err)
+		s.span.SetStatus(Error, err.Error())
+	case s.status >= 400:
+		s.span.SetStatus(Error, fmt.Sprintf("status %d", s.status))
+	}
+	s.span.End()
+}
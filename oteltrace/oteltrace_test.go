@@ -0,0 +1,79 @@
+package oteltrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/intwinelabs/gocosmosdb"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSpan struct {
+	attrs       map[string]interface{}
+	status      StatusCode
+	description string
+	errs        []error
+	ended       bool
+}
+
+func (s *fakeSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *fakeSpan) SetStatus(code StatusCode, description string) {
+	s.status, s.description = code, description
+}
+func (s *fakeSpan) RecordError(err error) { s.errs = append(s.errs, err) }
+func (s *fakeSpan) End()                  { s.ended = true }
+
+type fakeTracer struct {
+	spans []*fakeSpan
+}
+
+func (t *fakeTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	span := &fakeSpan{attrs: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func TestAdapter(t *testing.T) {
+	assert := assert.New(t)
+	fake := &fakeTracer{}
+	tracer := New(fake)
+
+	_, span := tracer.Start(context.Background(), "QueryDocuments")
+	span.SetAttribute(gocosmosdb.TraceAttrDatabase, "mydb")
+	span.SetAttribute(gocosmosdb.TraceAttrCollection, "mycoll")
+	span.SetAttribute(gocosmosdb.TraceAttrRequestCharge, 2.5)
+	span.SetAttribute(gocosmosdb.TraceAttrAttempt, 2)
+	span.SetAttribute(gocosmosdb.TraceAttrStatusCode, 200)
+	span.SetAttribute("custom", "value")
+	span.End(nil)
+	s := fake.spans[0]
+	assert.Equal(map[string]interface{}{
+		AttrDBSystem:      "cosmosdb",
+		AttrDBName:        "mydb",
+		AttrContainer:     "mycoll",
+		AttrRequestCharge: 2.5,
+		AttrResendCount:   1,
+		AttrStatusCode:    200,
+		"custom":          "value",
+	}, s.attrs)
+	assert.Equal(Unset, s.status)
+	assert.True(s.ended)
+}
+
+func TestAdapterStatus(t *testing.T) {
+	assert := assert.New(t)
+	fake := &fakeTracer{}
+	tracer := New(fake)
+
+	_, span := tracer.Start(context.Background(), "ReadDocument")
+	span.SetAttribute(gocosmosdb.TraceAttrStatusCode, 404)
+	span.End(nil)
+	assert.Equal(Error, fake.spans[0].status)
+	assert.Equal("status 404", fake.spans[0].description)
+
+	_, span = tracer.Start(context.Background(), "ReadDocument")
+	span.End(errors.New("connection refused"))
+	assert.Equal(Error, fake.spans[1].status)
+	assert.Len(fake.spans[1].errs, 1)
+}
@@ -1,13 +1,23 @@
 package gocosmosdb
 
-import "errors"
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
 
-// NewPagableQuery - Creates a pagable query that populates the passed docs interface
+// NewPagableQuery - Creates a pagable query that populates the passed docs interface. Across partitions ORDER BY
+// and aggregate queries return results ordered and aggregated per partition key range only, use
+// NewCrossPartitionQuery for them.
 func (c *CosmosDB) NewPagableQuery(coll string, query *QueryWithParameters, limit int, docs interface{}, opts ...CallOption) *PagableQuery {
 	return &PagableQuery{
 		client: c,
 		coll:   coll,
 		query:  query,
+		hash:   queryHash(coll, query),
 		limit:  Limit(limit),
 		offset: 0,
 		docs:   docs,
@@ -15,6 +25,29 @@ func (c *CosmosDB) NewPagableQuery(coll string, query *QueryWithParameters, limi
 	}
 }
 
+// NewPagableQueryFromCursor - Creates a pagable query resuming at the page a cursor returned by PagableQuery.Cursor
+// points to. The cursor must have been created for the same collection and query, and signed when the client has a
+// CursorKey.
+//	pg, err := client.NewPagableQueryFromCursor(coll, query, 10, &docs, r.URL.Query().Get("cursor"))
+func (c *CosmosDB) NewPagableQueryFromCursor(coll string, query *QueryWithParameters, limit int, docs interface{}, cursor string, opts ...CallOption) (*PagableQuery, error) {
+	if query == nil {
+		return nil, &ValidationError{"QueryWithParameters cannot be nil"}
+	}
+	position, err := decodeCursor(cursor, c.Config.CursorKey)
+	if err != nil {
+		return nil, err
+	}
+	if position.Query != queryHash(coll, query) {
+		return nil, &ValidationError{"cursor does not match the query"}
+	}
+	q := c.NewPagableQuery(coll, query, limit, docs, opts...)
+	if position.Continuation != "" {
+		q.offset = 1
+		q.continuation, q.sessionToken, q.activityID = position.Continuation, position.SessionToken, position.ActivityID
+	}
+	return q, nil
+}
+
 func (q *PagableQuery) doQuery(coll string, query *QueryWithParameters, docs interface{}, opts ...CallOption) (*Response, error) {
 	data := struct {
 		Documents interface{} `json:"Documents,omitempty"`
@@ -23,39 +56,39 @@ func (q *PagableQuery) doQuery(coll string, query *QueryWithParameters, docs int
 	if query != nil {
 		return q.client.client.queryWithParameters(coll+"docs/", query, &data, opts...)
 	}
-	return nil, errors.New("QueryWithParameters cannot be nil")
+	return nil, &ValidationError{"QueryWithParameters cannot be nil"}
 }
 
 // Next - marshals the next page of docs into the passed interface
 func (q *PagableQuery) Next() error {
 	if q.offset > 0 {
-		opts := append(q.opts, q.limit)
-		opts = append(opts, q.continuation)
-		opts = append(opts, q.sessionToken)
+		opts := append(q.opts[:len(q.opts):len(q.opts)], q.limit, Continuation(q.continuation), SessionToken(q.sessionToken), ActivityID(q.activityID))
 		resp, err := q.doQuery(q.coll, q.query, q.docs, opts...)
 		if err != nil {
 			return err
 		}
 		q.offset = q.offset + 1
 		if resp.Continuation() != "" {
-			q.continuation = Continuation(resp.Continuation())
+			q.continuation = resp.Continuation()
 		} else {
 			q.done = true
 		}
 	}
 	if q.offset == 0 {
-		opts := append(q.opts, q.limit)
+		opts := append(q.opts[:len(q.opts):len(q.opts)], q.limit)
 		resp, err := q.doQuery(q.coll, q.query, q.docs, opts...)
 		if err != nil {
 			return err
 		}
 		q.offset = q.offset + 1
 		if resp.Continuation() != "" {
-			q.continuation = Continuation(resp.Continuation())
+			q.continuation = resp.Continuation()
 		} else {
 			q.done = true
 		}
-		q.sessionToken = SessionToken(resp.SessionToken())
+		q.sessionToken = resp.SessionToken()
+		// every page of the query is part of the same logical operation
+		q.activityID = resp.ActivityID()
 	}
 	return nil
 }
@@ -64,3 +97,82 @@ func (q *PagableQuery) Next() error {
 func (q *PagableQuery) Done() bool {
 	return q.done
 }
+
+// Cursor - returns an opaque string resuming the query at the next page with NewPagableQueryFromCursor, empty when
+// the query is done. It is signed when the client has a CursorKey.
+//	next := "/orders?cursor=" + url.QueryEscape(pg.Cursor())
+func (q *PagableQuery) Cursor() string {
+	if q.done {
+		return ""
+	}
+	position := pageCursor{Query: q.hash}
+	if q.offset > 0 {
+		position.Continuation, position.SessionToken, position.ActivityID = q.continuation, q.sessionToken, q.activityID
+	}
+	return encodeCursor(position, q.client.Config.CursorKey)
+}
+
+// pageCursor - the position of a pagable query and a hash of the query it belongs to
+type pageCursor struct {
+	Continuation string `json:"c,omitempty"`
+	SessionToken string `json:"s,omitempty"`
+	ActivityID   string `json:"a,omitempty"`
+	Query        string `json:"q"`
+}
+
+// queryHash - identifies a query on a collection, its text and parameters
+func queryHash(coll string, query *QueryWithParameters) string {
+	h := sha256.New()
+	h.Write([]byte(strings.Trim(coll, "/")))
+	h.Write([]byte{0})
+	if query != nil {
+		h.Write([]byte(query.Query))
+		for _, p := range query.Parameters {
+			value, _ := json.Marshal(p.Value)
+			h.Write([]byte{0})
+			h.Write([]byte(p.Name))
+			h.Write([]byte{0})
+			h.Write(value)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// encodeCursor - encodes a cursor as base64, followed by its HMAC-SHA256 signature when a key is set
+func encodeCursor(position pageCursor, key []byte) string {
+	data, _ := json.Marshal(position)
+	cursor := base64.RawURLEncoding.EncodeToString(data)
+	if len(key) > 0 {
+		cursor += "." + base64.RawURLEncoding.EncodeToString(signCursor(cursor, key))
+	}
+	return cursor
+}
+
+// decodeCursor - decodes a cursor, it must be signed with the key when one is set
+func decodeCursor(cursor string, key []byte) (*pageCursor, error) {
+	payload, signature, signed := strings.Cut(cursor, ".")
+	if len(key) > 0 {
+		sig, err := base64.RawURLEncoding.DecodeString(signature)
+		if !signed || err != nil || !hmac.Equal(sig, signCursor(payload, key)) {
+			return nil, &ValidationError{"invalid cursor signature"}
+		}
+	} else if signed {
+		return nil, &ValidationError{"cannot verify a signed cursor without a CursorKey"}
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, &ValidationError{"invalid cursor"}
+	}
+	position := &pageCursor{}
+	if err = json.Unmarshal(data, position); err != nil || position.Query == "" {
+		return nil, &ValidationError{"invalid cursor"}
+	}
+	return position, nil
+}
+
+// signCursor - returns the HMAC-SHA256 of a cursor's payload
+func signCursor(payload string, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
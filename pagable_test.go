@@ -1,6 +1,10 @@
 package gocosmosdb
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -56,6 +60,90 @@ func TestPagable(t *testing.T) {
 	assert.IsType(&PagableQuery{}, pg)
 	pg.Next()
 	assert.Equal("SalesOrder1", docs[0].Id)
+	activityID := s.Header.Get(HeaderActivityID)
+	assert.NotEmpty(activityID)
 	pg.Next()
 	assert.Equal("SalesOrder2", docs[0].Id)
+	assert.Equal(activityID, s.Header.Get(HeaderActivityID))
+}
+
+func TestPagableCursor(t *testing.T) {
+	assert := assert.New(t)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get(HeaderContinuation) {
+		case "":
+			w.Header().Set(HeaderContinuation, "page2")
+			w.Header().Set(HeaderSessionToken, "0:7")
+			fmt.Fprint(w, `{"Documents": [{"id": "1"}]}`)
+		case "page2":
+			assert.Equal("0:7", r.Header.Get(HeaderSessionToken))
+			fmt.Fprint(w, `{"Documents": [{"id": "2"}]}`)
+		}
+	}))
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", CursorKey: []byte("secret")}, log)
+	query := Select().Where(Eq("c.type", "order")).Build()
+	docs := []testDoc{}
+	pg := client.NewPagableQuery("dbs/db/colls/coll/", query, 1, &docs)
+	assert.Nil(pg.Next())
+	cursor := pg.Cursor()
+	assert.NotEmpty(cursor)
+
+	resumed, err := client.NewPagableQueryFromCursor("dbs/db/colls/coll/", query, 1, &docs, cursor)
+	assert.Nil(err)
+	assert.Nil(resumed.Next())
+	assert.Equal("2", docs[0].Id)
+	assert.True(resumed.Done())
+	assert.Equal("", resumed.Cursor())
+
+	// a cursor for a query that has not started resumes at the first page
+	start := client.NewPagableQuery("dbs/db/colls/coll/", query, 1, &docs).Cursor()
+	resumed, err = client.NewPagableQueryFromCursor("dbs/db/colls/coll/", query, 1, &docs, start)
+	assert.Nil(err)
+	assert.Nil(resumed.Next())
+	assert.Equal("1", docs[0].Id)
+
+	other := Select().Where(Eq("c.type", "invoice")).Build()
+	_, err = client.NewPagableQueryFromCursor("dbs/db/colls/coll/", other, 1, &docs, cursor)
+	assert.True(IsValidationError(err))
+	_, err = client.NewPagableQueryFromCursor("dbs/db/colls/other/", query, 1, &docs, cursor)
+	assert.True(IsValidationError(err))
+
+	payload, signature, _ := strings.Cut(cursor, ".")
+	tampered := encodeCursor(pageCursor{Continuation: "page9", Query: queryHash("dbs/db/colls/coll/", query)}, nil)
+	_, err = client.NewPagableQueryFromCursor("dbs/db/colls/coll/", query, 1, &docs, tampered+"."+signature)
+	assert.True(IsValidationError(err))
+	_, err = client.NewPagableQueryFromCursor("dbs/db/colls/coll/", query, 1, &docs, payload)
+	assert.True(IsValidationError(err))
+	_, err = client.NewPagableQueryFromCursor("dbs/db/colls/coll/", query, 1, &docs, "garbage!")
+	assert.True(IsValidationError(err))
+
+	unsigned := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	_, err = unsigned.NewPagableQueryFromCursor("dbs/db/colls/coll/", query, 1, &docs, cursor)
+	assert.True(IsValidationError(err))
+	_, err = unsigned.NewPagableQueryFromCursor("dbs/db/colls/coll/", query, 1, &docs, payload)
+	assert.Nil(err)
+}
+
+func TestPagableCursorQuotedQuery(t *testing.T) {
+	assert := assert.New(t)
+	s, _ := pagedServer(`{"Documents": [{"id": "1"}]}`, `{"Documents": [{"id": "2"}]}`, `{"Documents": [{"id": "3"}]}`)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", CursorKey: []byte("secret")}, log)
+	query := &QueryWithParameters{
+		Query:      "SELECT * FROM c\nWHERE c[\"a\"] = @a AND c.path = 'x\\\\y'",
+		Parameters: []QueryParameter{{"@a", "b"}},
+	}
+	docs := []testDoc{}
+	pg := client.NewPagableQuery("dbs/db/colls/coll/", query, 1, &docs)
+	assert.Nil(pg.Next())
+	assert.Nil(pg.Next())
+	cursor := pg.Cursor()
+
+	// the cursor of a later page still matches the query
+	resumed, err := client.NewPagableQueryFromCursor("dbs/db/colls/coll/", query, 1, &docs, cursor)
+	assert.Nil(err)
+	assert.Nil(resumed.Next())
+	assert.Equal("3", docs[0].Id)
+	assert.True(resumed.Done())
 }
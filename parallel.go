@@ -0,0 +1,161 @@
+package gocosmosdb
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+)
+
+// rangePage - a page of results of a partition key range, token is the continuation it was fetched with and next
+// the continuation of the following page
+type rangePage struct {
+	token string
+	docs  []json.RawMessage
+	next  string
+	err   error
+}
+
+// load - reads the page of results of a partition key range starting at token
+func (q *CrossPartitionQuery) load(rangeID string, rewritten *QueryWithParameters, token string) rangePage {
+	data := struct {
+		Documents []json.RawMessage `json:"Documents"`
+	}{}
+	opts := q.opts
+	if q.pageSize > 0 {
+		opts = append(opts[:len(opts):len(opts)], Limit(q.pageSize))
+	}
+	if token != "" {
+		opts = append(opts[:len(opts):len(opts)], Continuation(token))
+	}
+	if q.ctx != nil {
+		opts = append(opts[:len(opts):len(opts)], WithContext(q.ctx))
+	}
+	resp, err := q.client.client.queryPartitionKeyRange(q.coll+"docs/", rangeID, rewritten, &data, opts...)
+	q.addCharge(resp)
+	if err != nil {
+		return rangePage{token: token, err: err}
+	}
+	return rangePage{token: token, docs: data.Documents, next: resp.Continuation()}
+}
+
+// prefetchRanges - starts reading the partition key ranges not yet exhausted in the background, the requests in
+// flight are limited by the degree of parallelism
+func (q *CrossPartitionQuery) prefetchRanges(cursors []*rangeCursor) {
+	q.ctx, q.cancel = context.WithCancel(optionsRequest(q.opts).context())
+	n := q.parallelism
+	if n < 0 || n > len(cursors) {
+		n = len(cursors)
+	}
+	pages := q.prefetch
+	if pages < 1 {
+		pages = 1
+	}
+	slots := make(chan struct{}, n)
+	for _, c := range cursors {
+		if !c.state.Done {
+			c.prefetch(q.ctx, slots, pages)
+		}
+	}
+}
+
+// prefetch - reads the pages of the range in a goroutine. A page waits to be received once pages are loaded ahead
+// of the results consumed, a request is only sent while it holds a slot.
+func (c *rangeCursor) prefetch(ctx context.Context, slots chan struct{}, pages int) {
+	ch := make(chan rangePage, pages-1)
+	c.pages = ch
+	go func(query *CrossPartitionQuery, rangeID string, rewritten *QueryWithParameters, token string) {
+		defer close(ch)
+		for {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			p := query.load(rangeID, rewritten, token)
+			<-slots
+			select {
+			case ch <- p:
+			case <-ctx.Done():
+				return
+			}
+			if p.err != nil || p.next == "" {
+				return
+			}
+			token = p.next
+		}
+	}(c.query, c.state.ID, c.rewritten, c.state.Token)
+}
+
+// receive - accepts a prefetched page. The range is read directly after an error so the page can be retried.
+func (c *rangeCursor) receive(p rangePage, ok bool) error {
+	if !ok {
+		c.pages = nil
+		return c.query.ctx.Err()
+	}
+	if p.err != nil {
+		c.pages = nil
+	}
+	return c.accept(p)
+}
+
+// buffered - whether the next result, or the end of the range, is known without waiting for a page
+func (c *rangeCursor) buffered() bool {
+	return c.state.Done || (c.fetched && (c.pos < len(c.page) || c.nextToken == ""))
+}
+
+// parallelSource - yields the results of the partition key ranges read in parallel in the order their pages arrive
+type parallelSource struct {
+	cursors []*rangeCursor
+}
+
+// ready - returns a cursor holding a result, it waits for the next page of any range when none is buffered
+func (s *parallelSource) ready() (*rangeCursor, json.RawMessage, error) {
+	for {
+		pending := []*rangeCursor{}
+		for _, c := range s.cursors {
+			if c.state.Done {
+				continue
+			}
+			if c.pages == nil || c.buffered() {
+				raw, ok, err := c.peek()
+				if err != nil {
+					return nil, nil, err
+				}
+				if ok {
+					return c, raw, nil
+				}
+				continue
+			}
+			pending = append(pending, c)
+		}
+		if len(pending) == 0 {
+			return nil, nil, nil
+		}
+		cases := make([]reflect.SelectCase, len(pending))
+		for i, c := range pending {
+			cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(c.pages)}
+		}
+		chosen, value, ok := reflect.Select(cases)
+		if err := pending[chosen].receive(value.Interface().(rangePage), ok); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+func (s *parallelSource) peek() (json.RawMessage, bool, error) {
+	c, raw, err := s.ready()
+	return raw, c != nil, err
+}
+
+func (s *parallelSource) next() (json.RawMessage, bool, error) {
+	c, raw, err := s.ready()
+	if c == nil {
+		return nil, false, err
+	}
+	c.pop()
+	return raw, true, nil
+}
+
+func (s *parallelSource) state(token *crossPartitionToken) {
+	token.Ranges = cursorStates(s.cursors)
+}
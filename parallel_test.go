@@ -0,0 +1,132 @@
+package gocosmosdb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const unorderedPlan = `{"queryInfo": {"distinctType": "None", "rewrittenQuery": ""}, "queryRanges": [{"min": "", "max": "FF", "isMinInclusive": true}]}`
+
+// rangeResults - returns n documents per partition key range, their ids are the range and the position in it
+func rangeResults(n int) map[string][]string {
+	results := map[string][]string{}
+	for _, rangeID := range []string{"0", "1", "2"} {
+		for i := 0; i < n; i++ {
+			results[rangeID] = append(results[rangeID], fmt.Sprintf(`{"id": "%s%d"}`, rangeID, i))
+		}
+	}
+	return results
+}
+
+// drain - reads every page of a query, returning the sorted ids of the results
+func drain(t *testing.T, query *CrossPartitionQuery) []string {
+	all := []string{}
+	for !query.Done() {
+		docs := []Document{}
+		assert.Nil(t, query.Next(&docs))
+		for _, d := range docs {
+			all = append(all, d.Id)
+		}
+	}
+	sort.Strings(all)
+	return all
+}
+
+func TestParallelMaxDegreeOfParallelism(t *testing.T) {
+	assert := assert.New(t)
+	s := newPartitionedServer(unorderedPlan, rangeResults(3))
+	s.delay = 20 * time.Millisecond
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().Build(), 1).SetMaxDegreeOfParallelism(2)
+	assert.Equal([]string{"00", "01", "02", "10", "11", "12", "20", "21", "22"}, drain(t, query))
+	assert.True(s.peak() <= 2)
+	assert.Equal(float64(11), query.RequestCharge())
+
+	query = client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().Build(), 1).SetMaxDegreeOfParallelism(-1).SetPrefetch(3)
+	assert.Len(drain(t, query), 9)
+	assert.True(s.peak() <= 3)
+}
+
+func TestParallelYieldsPagesAsTheyArrive(t *testing.T) {
+	assert := assert.New(t)
+	s := newPartitionedServer(unorderedPlan, rangeResults(2))
+	s.slow, s.delay = "0", 100*time.Millisecond
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().Build(), 4).SetMaxDegreeOfParallelism(-1)
+	docs := []Document{}
+	assert.Nil(query.Next(&docs))
+	first := []string{}
+	for _, d := range docs {
+		first = append(first, d.Id)
+	}
+	sort.Strings(first)
+	assert.Equal([]string{"10", "11", "20", "21"}, first)
+	assert.Nil(query.Next(&docs))
+	assert.Equal("0001", ids(docs))
+	assert.True(query.Done())
+}
+
+func TestParallelOrderBy(t *testing.T) {
+	assert := assert.New(t)
+	s := newPartitionedServer(orderByPlan, orderByResults())
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().OrderBy("c.name").Build(), 2).SetMaxDegreeOfParallelism(-1)
+	pages := []string{}
+	for !query.Done() {
+		docs := []Document{}
+		assert.Nil(query.Next(&docs))
+		pages = append(pages, ids(docs))
+	}
+	assert.Equal([]string{"ua", "bc", "de", "fg", "h"}, pages)
+}
+
+func TestParallelContinuation(t *testing.T) {
+	assert := assert.New(t)
+	s := newPartitionedServer(unorderedPlan, rangeResults(3))
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	all := []string{}
+	continuation := ""
+	for {
+		query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().Build(), 2).SetMaxDegreeOfParallelism(2).SetContinuation(continuation)
+		docs := []Document{}
+		assert.Nil(query.Next(&docs))
+		for _, d := range docs {
+			all = append(all, d.Id)
+		}
+		query.Close()
+		var err error
+		continuation, err = query.Continuation()
+		assert.Nil(err)
+		if continuation == "" {
+			break
+		}
+	}
+	sort.Strings(all)
+	assert.Equal("00 01 02 10 11 12 20 21 22", strings.Join(all, " "))
+}
+
+func TestParallelClose(t *testing.T) {
+	assert := assert.New(t)
+	s := newPartitionedServer(unorderedPlan, rangeResults(5))
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().Build(), 1).SetMaxDegreeOfParallelism(-1)
+	docs := []Document{}
+	assert.Nil(query.Next(&docs))
+	query.Close()
+	var err error
+	for i := 0; i < 15 && err == nil; i++ {
+		err = query.Next(&docs)
+	}
+	assert.NotNil(err)
+	assert.False(query.Done())
+}
@@ -0,0 +1,311 @@
+package gocosmosdb
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// QueryBuilder - builds a parameterized SQL query, values are always passed as @p0, @p1, ... parameters
+//	query := gocosmosdb.Select("c.id", "c.name").From("c").
+//		Where(gocosmosdb.Eq("c.status", status)).
+//		And(gocosmosdb.ArrayContains("c.tags", "blue")).
+//		OrderBy("c.name").OffsetLimit(0, 10).Build()
+//	_, err := client.QueryDocumentsWithParameters(coll, query, &docs)
+type QueryBuilder struct {
+	fields   []string
+	distinct bool
+	value    bool
+	top      int
+	alias    string
+	where    Condition
+	orderBy  []string
+	offset   int
+	limit    int
+	limitSet bool
+}
+
+// Condition - a filter of a query built by a QueryBuilder
+type Condition func(p *queryParams) string
+
+// queryParams - the parameters of a query being built
+type queryParams struct {
+	params []QueryParameter
+}
+
+// add - adds a parameter and returns its name
+func (p *queryParams) add(value interface{}) string {
+	name := "@p" + strconv.Itoa(len(p.params))
+	p.params = append(p.params, QueryParameter{Name: name, Value: value})
+	return name
+}
+
+// Select - starts a query selecting the passed paths, or * when none are passed. Paths are quoted
+// as needed eg. "c.first-name" becomes c['first-name'], expressions and paths containing a
+// parenthesis or bracket are used as is.
+func Select(fields ...string) *QueryBuilder {
+	return &QueryBuilder{fields: fields, alias: "c"}
+}
+
+// SelectValue - starts a query selecting the value of a single path eg. SELECT VALUE c.id
+func SelectValue(field string) *QueryBuilder {
+	q := Select(field)
+	q.value = true
+	return q
+}
+
+// Distinct - selects distinct results only
+func (q *QueryBuilder) Distinct() *QueryBuilder {
+	q.distinct = true
+	return q
+}
+
+// Top - returns at most n results
+func (q *QueryBuilder) Top(n int) *QueryBuilder {
+	q.top = n
+	return q
+}
+
+// From - sets the alias of the collection, defaults to c
+func (q *QueryBuilder) From(alias string) *QueryBuilder {
+	q.alias = alias
+	return q
+}
+
+// Where - filters the results, calling it again adds the condition with AND
+func (q *QueryBuilder) Where(cond Condition) *QueryBuilder {
+	return q.And(cond)
+}
+
+// And - adds a condition the results must also match
+func (q *QueryBuilder) And(cond Condition) *QueryBuilder {
+	if q.where == nil {
+		q.where = cond
+	} else {
+		q.where = And(q.where, cond)
+	}
+	return q
+}
+
+// Or - adds a condition the results may match instead of the conditions added so far
+func (q *QueryBuilder) Or(cond Condition) *QueryBuilder {
+	if q.where == nil {
+		q.where = cond
+	} else {
+		q.where = Or(q.where, cond)
+	}
+	return q
+}
+
+// OrderBy - sorts the results by a path in ascending order, calling it again adds a sort path
+func (q *QueryBuilder) OrderBy(path string) *QueryBuilder {
+	q.orderBy = append(q.orderBy, quotePath(path)+" ASC")
+	return q
+}
+
+// OrderByDesc - sorts the results by a path in descending order
+func (q *QueryBuilder) OrderByDesc(path string) *QueryBuilder {
+	q.orderBy = append(q.orderBy, quotePath(path)+" DESC")
+	return q
+}
+
+// OffsetLimit - skips offset results and returns at most limit results
+func (q *QueryBuilder) OffsetLimit(offset, limit int) *QueryBuilder {
+	q.offset, q.limit, q.limitSet = offset, limit, true
+	return q
+}
+
+// Build - returns the query and its parameters
+func (q *QueryBuilder) Build() *QueryWithParameters {
+	p := &queryParams{params: []QueryParameter{}}
+	var sql strings.Builder
+	sql.WriteString("SELECT ")
+	if q.distinct {
+		sql.WriteString("DISTINCT ")
+	}
+	if q.top > 0 {
+		sql.WriteString("TOP " + strconv.Itoa(q.top) + " ")
+	}
+	if q.value {
+		sql.WriteString("VALUE ")
+	}
+	if len(q.fields) == 0 {
+		sql.WriteString("*")
+	} else {
+		fields := make([]string, len(q.fields))
+		for i, f := range q.fields {
+			fields[i] = quotePath(f)
+		}
+		sql.WriteString(strings.Join(fields, ", "))
+	}
+	alias := q.alias
+	if alias == "" {
+		alias = "c"
+	}
+	sql.WriteString(" FROM " + alias)
+	if q.where != nil {
+		sql.WriteString(" WHERE " + q.where(p))
+	}
+	if len(q.orderBy) > 0 {
+		sql.WriteString(" ORDER BY " + strings.Join(q.orderBy, ", "))
+	}
+	if q.limitSet {
+		sql.WriteString(fmt.Sprintf(" OFFSET %d LIMIT %d", q.offset, q.limit))
+	}
+	return &QueryWithParameters{Query: sql.String(), Parameters: p.params}
+}
+
+// String - returns the SQL text of the query
+func (q *QueryBuilder) String() string {
+	return q.Build().Query
+}
+
+// Eq - matches when the path equals the value
+func Eq(path string, value interface{}) Condition {
+	return compare(path, "=", value)
+}
+
+// Ne - matches when the path does not equal the value
+func Ne(path string, value interface{}) Condition {
+	return compare(path, "!=", value)
+}
+
+// Gt - matches when the path is greater than the value
+func Gt(path string, value interface{}) Condition {
+	return compare(path, ">", value)
+}
+
+// Gte - matches when the path is greater than or equal to the value
+func Gte(path string, value interface{}) Condition {
+	return compare(path, ">=", value)
+}
+
+// Lt - matches when the path is less than the value
+func Lt(path string, value interface{}) Condition {
+	return compare(path, "<", value)
+}
+
+// Lte - matches when the path is less than or equal to the value
+func Lte(path string, value interface{}) Condition {
+	return compare(path, "<=", value)
+}
+
+func compare(path, op string, value interface{}) Condition {
+	return func(p *queryParams) string {
+		return quotePath(path) + " " + op + " " + p.add(value)
+	}
+}
+
+// In - matches when the path equals one of the values, with no values it matches nothing
+func In(path string, values ...interface{}) Condition {
+	return func(p *queryParams) string {
+		if len(values) == 0 {
+			return "false"
+		}
+		names := make([]string, len(values))
+		for i, v := range values {
+			names[i] = p.add(v)
+		}
+		return quotePath(path) + " IN (" + strings.Join(names, ", ") + ")"
+	}
+}
+
+// ArrayContains - matches when the array at path contains the value
+func ArrayContains(path string, value interface{}) Condition {
+	return function("ARRAY_CONTAINS", path, value)
+}
+
+// ArrayContainsPartial - matches when the array at path contains an object with the fields of the value
+func ArrayContainsPartial(path string, value interface{}) Condition {
+	return func(p *queryParams) string {
+		return "ARRAY_CONTAINS(" + quotePath(path) + ", " + p.add(value) + ", true)"
+	}
+}
+
+// StartsWith - matches when the string at path starts with the prefix
+func StartsWith(path string, prefix string) Condition {
+	return function("STARTSWITH", path, prefix)
+}
+
+// IsDefined - matches when the path is defined
+func IsDefined(path string) Condition {
+	return func(p *queryParams) string {
+		return "IS_DEFINED(" + quotePath(path) + ")"
+	}
+}
+
+func function(name, path string, value interface{}) Condition {
+	return func(p *queryParams) string {
+		return name + "(" + quotePath(path) + ", " + p.add(value) + ")"
+	}
+}
+
+// And - matches when all conditions match
+func And(conds ...Condition) Condition {
+	return join(" AND ", conds)
+}
+
+// Or - matches when any condition matches
+func Or(conds ...Condition) Condition {
+	return join(" OR ", conds)
+}
+
+// Not - matches when the condition does not match
+func Not(cond Condition) Condition {
+	return func(p *queryParams) string {
+		return "NOT (" + cond(p) + ")"
+	}
+}
+
+func join(op string, conds []Condition) Condition {
+	return func(p *queryParams) string {
+		parts := make([]string, 0, len(conds))
+		for _, c := range conds {
+			if c != nil {
+				parts = append(parts, c(p))
+			}
+		}
+		if len(parts) == 1 {
+			return parts[0]
+		}
+		return "(" + strings.Join(parts, op) + ")"
+	}
+}
+
+// reservedWords - keywords that must be quoted when used as a property name
+var reservedWords = map[string]bool{
+	"AND": true, "ARRAY": true, "AS": true, "ASC": true, "BETWEEN": true, "BY": true, "DESC": true,
+	"DISTINCT": true, "ESCAPE": true, "EXISTS": true, "FALSE": true, "FROM": true, "GROUP": true,
+	"IN": true, "IS": true, "JOIN": true, "LIKE": true, "LIMIT": true, "NOT": true, "NULL": true,
+	"OFFSET": true, "OR": true, "ORDER": true, "SELECT": true, "TOP": true, "TRUE": true, "UDF": true,
+	"UNDEFINED": true, "VALUE": true, "WHERE": true,
+}
+
+var (
+	identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	indexRegexp      = regexp.MustCompile(`^[0-9]+$`)
+)
+
+// quotePath - quotes the segments of a dotted path that are not identifiers or are reserved words eg. c.address.zip-code
+// becomes c.address['zip-code'] and c.tags.0 becomes c.tags[0]. Expressions and already quoted
+// paths are returned as is.
+func quotePath(path string) string {
+	if path == "*" || strings.ContainsAny(path, "()[") {
+		return path
+	}
+	segments := strings.Split(path, ".")
+	var quoted strings.Builder
+	quoted.WriteString(segments[0])
+	for _, s := range segments[1:] {
+		switch {
+		case identifierRegexp.MatchString(s) && !reservedWords[strings.ToUpper(s)]:
+			quoted.WriteString("." + s)
+		case indexRegexp.MatchString(s):
+			quoted.WriteString("[" + s + "]")
+		default:
+			quoted.WriteString(`['` + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + `']`)
+		}
+	}
+	return quoted.String()
+}
@@ -0,0 +1,75 @@
+package gocosmosdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	assert := assert.New(t)
+	q := Select("c.id", "c.name").From("c").
+		Where(Eq("c.status", "active")).
+		And(Or(Gt("c.age", 18), In("c.role", "admin", "owner"))).
+		And(ArrayContains("c.tags", "blue")).
+		OrderBy("c.name").OrderByDesc("c._ts").
+		OffsetLimit(10, 5).Build()
+	assert.Equal("SELECT c.id, c.name FROM c WHERE ((c.status = @p0 AND (c.age > @p1 OR c.role IN (@p2, @p3))) AND ARRAY_CONTAINS(c.tags, @p4)) ORDER BY c.name ASC, c._ts DESC OFFSET 10 LIMIT 5", q.Query)
+	assert.Equal([]QueryParameter{
+		{"@p0", "active"},
+		{"@p1", 18},
+		{"@p2", "admin"},
+		{"@p3", "owner"},
+		{"@p4", "blue"},
+	}, q.Parameters)
+}
+
+func TestQueryBuilderDefaults(t *testing.T) {
+	assert := assert.New(t)
+	q := Select().Build()
+	assert.Equal("SELECT * FROM c", q.Query)
+	assert.NotNil(q.Parameters)
+	assert.Len(q.Parameters, 0)
+
+	q = SelectValue("COUNT(1)").Distinct().Top(3).From("r").Where(In("r.id")).Or(Not(IsDefined("r.deleted"))).Build()
+	assert.Equal("SELECT DISTINCT TOP 3 VALUE COUNT(1) FROM r WHERE (false OR NOT (IS_DEFINED(r.deleted)))", q.Query)
+	assert.Equal("SELECT * FROM c WHERE STARTSWITH(c.name, @p0)", Select().Where(StartsWith("c.name", "a")).String())
+
+	// the zero value selects everything without an OFFSET LIMIT
+	assert.Equal("SELECT * FROM c", (&QueryBuilder{}).Build().Query)
+	assert.Equal("SELECT * FROM c OFFSET 0 LIMIT 0", Select().OffsetLimit(0, 0).Build().Query)
+}
+
+func TestQuotePath(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal("c.address.city", quotePath("c.address.city"))
+	assert.Equal(`c.address['zip-code']`, quotePath("c.address.zip-code"))
+	assert.Equal(`c['value'].tags[0]`, quotePath("c.value.tags.0"))
+	assert.Equal(`c['say \'hi\'']`, quotePath(`c.say 'hi'`))
+	assert.Equal(`c["a b"]`, quotePath(`c["a b"]`))
+}
+
+func TestQueryBuilderWithClient(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(`{"_rid": "d9RzAJRFKgw=", "Documents": [{"id": "1"}], "_count": 1}`)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	docs := []Document{}
+	_, err := client.QueryDocumentsWithParameters("dbs/db/colls/coll/", Select().Where(Eq("c.id", "1")).Build(), &docs)
+	assert.Nil(err)
+	assert.Len(docs, 1)
+	assert.JSONEq(`{"query": "SELECT * FROM c WHERE c.id = @p0", "parameters": [{"name": "@p0", "value": "1"}]}`, s.Body)
+}
+
+func TestQueryBuilderQuotedPathWithClient(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(`{"_rid": "d9RzAJRFKgw=", "Documents": [], "_count": 0}`)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	query := Select(`c.say 'hi'`).Where(Eq(`c.a "b"`, "x")).Build()
+	_, err := client.QueryDocumentsWithParameters("dbs/db/colls/coll/", query, &[]Document{})
+	assert.Nil(err)
+	// the quoted paths reach the service as built, escaped once by the JSON body
+	assert.JSONEq(`{"query": "SELECT c['say \\'hi\\''] FROM c WHERE c['a \"b\"'] = @p0", "parameters": [{"name": "@p0", "value": "x"}]}`, s.Body)
+	assert.Equal(`SELECT c['say \'hi\''] FROM c WHERE c['a "b"'] = @p0`, query.Query)
+}
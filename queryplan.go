@@ -0,0 +1,132 @@
+package gocosmosdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// SupportedQueryFeatures - the query features the client can execute across partitions
+const SupportedQueryFeatures = "Aggregate, CompositeAggregate, Distinct, GroupBy, MultipleAggregates, MultipleOrderBy, NonValueAggregate, OffsetAndLimit, OrderBy, Top"
+
+// orderByFilter - the placeholder of rewritten ORDER BY queries for the filter used to resume them
+const orderByFilter = "{documentdb-formattableorderbyquery-filter}"
+
+// queryPlan - how the service wants a query executed across partitions
+type queryPlan struct {
+	Version     int          `json:"partitionedQueryExecutionInfoVersion"`
+	QueryInfo   queryInfo    `json:"queryInfo"`
+	QueryRanges []queryRange `json:"queryRanges"`
+}
+
+// queryInfo - the work the client has to do on the results of every partition key range
+type queryInfo struct {
+	DistinctType                string             `json:"distinctType"`
+	Top                         *int               `json:"top"`
+	Offset                      *int               `json:"offset"`
+	Limit                       *int               `json:"limit"`
+	OrderBy                     []string           `json:"orderBy"`
+	OrderByExpressions          []string           `json:"orderByExpressions"`
+	GroupByExpressions          []string           `json:"groupByExpressions"`
+	GroupByAliases              []string           `json:"groupByAliases"`
+	Aggregates                  []string           `json:"aggregates"`
+	GroupByAliasToAggregateType map[string]*string `json:"groupByAliasToAggregateType"`
+	RewrittenQuery              string             `json:"rewrittenQuery"`
+	HasSelectValue              bool               `json:"hasSelectValue"`
+}
+
+// queryRange - a range of effective partition keys a query targets
+type queryRange struct {
+	Min            string `json:"min"`
+	Max            string `json:"max"`
+	IsMinInclusive bool   `json:"isMinInclusive"`
+	IsMaxInclusive bool   `json:"isMaxInclusive"`
+}
+
+// overlaps - whether the query range targets the partition key range, which includes its min and excludes its max
+func (qr queryRange) overlaps(pkRange PartitionKeyRange) bool {
+	if qr.Max < pkRange.MinInclusive || (qr.Max == pkRange.MinInclusive && !qr.IsMaxInclusive) {
+		return false
+	}
+	return qr.Min < pkRange.MaxInclusive
+}
+
+// rewrittenQuery - returns the query to run against every partition key range
+func (p *queryPlan) rewrittenQuery(query *QueryWithParameters) *QueryWithParameters {
+	if p.QueryInfo.RewrittenQuery == "" {
+		return query
+	}
+	return &QueryWithParameters{
+		Query:      strings.Replace(p.QueryInfo.RewrittenQuery, orderByFilter, "true", -1),
+		Parameters: query.Parameters,
+	}
+}
+
+// queryPlan - asks the service how to execute a query across partitions
+func (c *apiClient) queryPlan(link string, query *QueryWithParameters, opts ...CallOption) (*queryPlan, *Response, error) {
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest("POST", path(c.uri, link), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	r := ResourceRequest(link, req)
+	r.rOperation = "QueryPlan"
+	if err = c.apply(r, opts); err != nil {
+		return nil, nil, err
+	}
+	r.QueryHeaders(len(body))
+	r.Header.Set(HeaderIsQueryPlan, "true")
+	r.Header.Set(HeaderSupportedQueryFeatures, SupportedQueryFeatures)
+	r.Header.Set(HeaderQueryVersion, SupportedQueryVersion)
+	r.Header.Set(HeaderCrossPartition, "true")
+	plan := &queryPlan{}
+	resp, err := c.do(r, http.StatusOK, plan)
+	if err != nil {
+		return nil, resp, err
+	}
+	return plan, resp, nil
+}
+
+// queryPartitionKeyRange - runs a query against one partition key range
+func (c *apiClient) queryPartitionKeyRange(link, pkRangeID string, query *QueryWithParameters, ret interface{}, opts ...CallOption) (*Response, error) {
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", path(c.uri, link), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	r := ResourceRequest(link, req)
+	if err = c.apply(r, opts); err != nil {
+		return nil, err
+	}
+	r.QueryHeaders(len(body))
+	r.Header.Set(HeaderPartitionKeyRangeID, pkRangeID)
+	r.Header.Set(HeaderCrossPartition, "true")
+	r.Header.Del(HeaderPartitionKey)
+	return c.do(r, http.StatusOK, ret)
+}
+
+// targetRanges - returns the partition key ranges a plan targets, sorted by their min
+func (p *queryPlan) targetRanges(pkRanges []PartitionKeyRange) []PartitionKeyRange {
+	sort.Slice(pkRanges, func(i, j int) bool { return pkRanges[i].MinInclusive < pkRanges[j].MinInclusive })
+	if len(p.QueryRanges) == 0 {
+		return pkRanges
+	}
+	var targets []PartitionKeyRange
+	for _, pkRange := range pkRanges {
+		for _, qr := range p.QueryRanges {
+			if qr.overlaps(pkRange) {
+				targets = append(targets, pkRange)
+				break
+			}
+		}
+	}
+	return targets
+}
@@ -0,0 +1,150 @@
+package gocosmosdb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRUBudgetExceeded - returned when a request is made after its context's request unit budget has been spent
+var ErrRUBudgetExceeded = errors.New("request unit budget exceeded")
+
+// ruLimiter - a token bucket denominated in request units. Requests are charged after they complete,
+// so the bucket can go into debt, and new requests wait until the debt has been paid off.
+type ruLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRULimiter - creates a limiter refilling at rate RU/s up to burst RUs
+func newRULimiter(rate, burst float64) *ruLimiter {
+	if burst <= 0 {
+		burst = rate
+	}
+	return &ruLimiter{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// refill - adds the tokens earned since the last refill, the lock must be held
+func (l *ruLimiter) refill(now time.Time) {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
+
+// wait - blocks until the bucket is out of debt or the context is done
+func (l *ruLimiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		l.refill(time.Now())
+		if l.tokens > 0 {
+			l.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration((-l.tokens/l.rate)*float64(time.Second)) + time.Millisecond
+		l.mu.Unlock()
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// charge - removes the request units consumed by a request from the bucket
+func (l *ruLimiter) charge(rus float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.tokens -= rus
+}
+
+// available - returns the request units currently in the bucket, negative when in debt
+func (l *ruLimiter) available() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	return l.tokens
+}
+
+// RUBudget - the request units a logical operation, eg. a multi-page query, may spend
+type RUBudget struct {
+	mu    sync.Mutex
+	limit float64
+	spent float64
+}
+
+type ruBudgetKey struct{}
+
+// WithRUBudget - returns a context carrying a request unit budget, pass it to requests using WithContext.
+// Once the budget is spent further requests with the context fail with ErrRUBudgetExceeded.
+//	ctx, budget := gocosmosdb.WithRUBudget(ctx, 1000)
+//	pg := client.NewPagableQuery(coll, query, 100, &docs, gocosmosdb.WithContext(ctx))
+func WithRUBudget(ctx context.Context, rus float64) (context.Context, *RUBudget) {
+	budget := &RUBudget{limit: rus}
+	return context.WithValue(ctx, ruBudgetKey{}, budget), budget
+}
+
+// ruBudgetFromContext - returns the budget of a context, if any
+func ruBudgetFromContext(ctx context.Context) *RUBudget {
+	if ctx == nil {
+		return nil
+	}
+	budget, _ := ctx.Value(ruBudgetKey{}).(*RUBudget)
+	return budget
+}
+
+// Spent - returns the request units spent so far
+func (b *RUBudget) Spent() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spent
+}
+
+// Remaining - returns the request units left to spend
+func (b *RUBudget) Remaining() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.limit - b.spent
+}
+
+// charge - adds the request units consumed by a request
+func (b *RUBudget) charge(rus float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.spent += rus
+}
+
+// waitForRUs - checks the request's budget and waits for the client's limiter before a request is sent
+func (c *apiClient) waitForRUs(r *Request) error {
+	if budget := ruBudgetFromContext(r.rContext); budget != nil && budget.Remaining() <= 0 {
+		return ErrRUBudgetExceeded
+	}
+	if c.limiter != nil {
+		return c.limiter.wait(r.context())
+	}
+	return nil
+}
+
+// chargeRUs - charges the request units of a response to the client's limiter and the request's budget
+func (c *apiClient) chargeRUs(r *Request, header http.Header) {
+	rus, err := strconv.ParseFloat(header.Get(HeaderRequestCharge), 64)
+	if err != nil || rus <= 0 {
+		return
+	}
+	if c.limiter != nil {
+		c.limiter.charge(rus)
+	}
+	if budget := ruBudgetFromContext(r.rContext); budget != nil {
+		budget.charge(rus)
+	}
+}
@@ -0,0 +1,70 @@
+package gocosmosdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func chargingServer(rus string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(HeaderRequestCharge, rus)
+		w.Header().Set(HeaderContinuation, "next")
+		fmt.Fprintln(w, `{"Documents": [{"id": "1"}], "_count": 1}`)
+	}))
+}
+
+func TestRULimiter(t *testing.T) {
+	assert := assert.New(t)
+	limiter := newRULimiter(1000, 0)
+	assert.InDelta(1000, limiter.available(), 1)
+
+	// going into debt makes the next request wait until it is paid off
+	limiter.charge(1050)
+	start := time.Now()
+	assert.Nil(limiter.wait(context.Background()))
+	assert.True(time.Since(start) >= 40*time.Millisecond)
+
+	// waiting respects the context
+	limiter.charge(10000)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, limiter.wait(ctx))
+}
+
+func TestClientRULimit(t *testing.T) {
+	assert := assert.New(t)
+	s := chargingServer("50")
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", RULimit: 1000, RUBurst: 60}, log)
+	docs := []Document{}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		_, err := client.ReadDocuments("dbs/d9RzAA==/colls/d9RzAJRFKgw=/", &docs)
+		assert.Nil(err)
+	}
+	// 150 RUs against a 60 RU burst refilling at 1000 RU/s
+	assert.True(time.Since(start) >= 30*time.Millisecond)
+	assert.True(client.client.limiter.available() < 60)
+}
+
+func TestRUBudget(t *testing.T) {
+	assert := assert.New(t)
+	s := chargingServer("10.5")
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	ctx, budget := WithRUBudget(context.Background(), 20)
+	docs := []Document{}
+	query := &QueryWithParameters{Query: "SELECT * FROM root r"}
+	pg := client.NewPagableQuery("dbs/d9RzAA==/colls/d9RzAJRFKgw=/", query, 1, &docs, WithContext(ctx))
+	assert.Nil(pg.Next())
+	assert.Nil(pg.Next())
+	assert.Equal(21.0, budget.Spent())
+	assert.Equal(-1.0, budget.Remaining())
+	assert.Equal(ErrRUBudgetExceeded, pg.Next())
+}
@@ -0,0 +1,122 @@
+package gocosmosdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted - the value logged in place of secrets and redacted document fields
+const Redacted = "REDACTED"
+
+// redactedFields - document fields always redacted, "_token" holds the resource token of a permission
+var redactedFields = []string{"_token"}
+
+// redactor - removes authorization signatures, resource tokens and configured document fields from debug output
+type redactor struct {
+	fields map[string]bool
+}
+
+// newRedactor - returns a redactor for the passed document fields, on top of redactedFields
+func newRedactor(fields []string) *redactor {
+	rd := &redactor{fields: map[string]bool{}}
+	for _, f := range append(redactedFields, fields...) {
+		rd.fields[f] = true
+	}
+	return rd
+}
+
+// header - returns a copy of the header with the authorization signature redacted
+func (rd *redactor) header(h http.Header) http.Header {
+	h = h.Clone()
+	if auth := h.Get(HeaderAuth); auth != "" {
+		h.Set(HeaderAuth, redactAuth(auth))
+	}
+	return h
+}
+
+// redactAuth - replaces the signature of an authorization header, master key, resource or aad, with Redacted
+func redactAuth(auth string) string {
+	unescaped, err := url.QueryUnescape(auth)
+	if err != nil {
+		return Redacted
+	}
+	i := strings.Index(unescaped, "sig=")
+	if i < 0 {
+		return Redacted
+	}
+	return url.QueryEscape(unescaped[:i+len("sig=")] + Redacted)
+}
+
+// body - returns the JSON body with the redacted fields replaced, bodies that are not JSON are returned unchanged
+func (rd *redactor) body(body []byte) []byte {
+	if len(body) == 0 {
+		return body
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return body
+	}
+	if !rd.walk(v) {
+		return body
+	}
+	redacted, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// walk - redacts the fields of v in place, returning whether anything was redacted
+func (rd *redactor) walk(v interface{}) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if rd.fields[k] {
+				v[k] = Redacted
+				changed = true
+			} else if rd.walk(child) {
+				changed = true
+			}
+		}
+	case []interface{}:
+		for _, child := range v {
+			if rd.walk(child) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+// request - returns a copy of the request safe to log, the body of the original request is preserved
+func (rd *redactor) request(r *http.Request) (*http.Request, error) {
+	body, err := readBody(r)
+	if err != nil {
+		return nil, err
+	}
+	clone := r.Clone(r.Context())
+	clone.Header = rd.header(r.Header)
+	if body != nil {
+		clone.Body = ioutil.NopCloser(bytes.NewReader(rd.body(body)))
+	}
+	return clone, nil
+}
+
+// readBody - reads the body of a request and replaces it so it can be sent, nil when there is no body
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
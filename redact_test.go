@@ -0,0 +1,45 @@
+package gocosmosdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactAuth(t *testing.T) {
+	assert := assert.New(t)
+	auth := url.QueryEscape("type=master&ver=1.0&sig=c2VjcmV0")
+	assert.Equal(url.QueryEscape("type=master&ver=1.0&sig=REDACTED"), redactAuth(auth))
+	auth = url.QueryEscape("type=resource&ver=1.0&sig=dG9rZW4=")
+	assert.Equal(url.QueryEscape("type=resource&ver=1.0&sig=REDACTED"), redactAuth(auth))
+	assert.Equal(Redacted, redactAuth("garbage"))
+}
+
+func TestRedactBody(t *testing.T) {
+	assert := assert.New(t)
+	rd := newRedactor([]string{"ssn"})
+	body := rd.body([]byte(`{"id": "1", "ssn": "123", "_token": "tok", "kids": [{"ssn": "456"}]}`))
+	assert.JSONEq(`{"id": "1", "ssn": "REDACTED", "_token": "REDACTED", "kids": [{"ssn": "REDACTED"}]}`, string(body))
+	assert.Equal(`{"id": "1"}`, string(rd.body([]byte(`{"id": "1"}`))))
+	assert.Equal("not json", string(rd.body([]byte("not json"))))
+}
+
+func TestRedactRequest(t *testing.T) {
+	assert := assert.New(t)
+	r, _ := http.NewRequest("POST", "https://example.com/dbs/db/colls/coll/docs", bytes.NewBufferString(`{"ssn": "123"}`))
+	r.Header.Set(HeaderAuth, url.QueryEscape("type=master&ver=1.0&sig=c2VjcmV0"))
+	safe, err := newRedactor([]string{"ssn"}).request(r)
+	assert.Nil(err)
+	assert.NotContains(safe.Header.Get(HeaderAuth), "c2VjcmV0")
+	body, _ := ioutil.ReadAll(safe.Body)
+	assert.JSONEq(`{"ssn": "REDACTED"}`, string(body))
+
+	// the original request is untouched
+	assert.Contains(r.Header.Get(HeaderAuth), "c2VjcmV0")
+	body, _ = ioutil.ReadAll(r.Body)
+	assert.Equal(`{"ssn": "123"}`, string(body))
+}
@@ -11,42 +11,171 @@ import (
 
 // RequestError
 type RequestError struct {
-	Code       string        `json:"code"`
-	StatusCode int           `json:"statusCode"`
-	Message    string        `json:"message"`
-	RId        string        `json:"rId"`
-	RType      string        `json:"rType`
-	Request    *http.Request `json:"request"`
+	Code          string        `json:"code"`
+	StatusCode    int           `json:"statusCode"`
+	Message       string        `json:"message"`
+	RId           string        `json:"rId"`
+	RType         string        `json:"rType"`
+	Request       *http.Request `json:"request"`
+	SubStatus     int           `json:"subStatus,omitempty"`
+	RetryAfter    time.Duration `json:"retryAfter,omitempty"`
+	ActivityID    string        `json:"activityId,omitempty"`
+	RequestCharge float64       `json:"requestCharge,omitempty"`
+	Diagnostics   *Diagnostics  `json:"diagnostics,omitempty"`
 }
 
 // Implement Error function
 func (e RequestError) Error() string {
+	if e.Diagnostics != nil {
+		return fmt.Sprintf("%v, %v (%v)", e.Code, e.Message, e.Diagnostics)
+	}
 	return fmt.Sprintf("%v, %v", e.Code, e.Message)
 }
 
+type activityIDKey struct{}
+
+// WithActivityID - returns a context carrying the activity ID used for requests made with it, pass it to requests
+// using WithContext. When id is empty a new activity ID is generated, read it back with ActivityIDFromContext.
+func WithActivityID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		id = genId()
+	}
+	return context.WithValue(ctx, activityIDKey{}, id)
+}
+
+// ActivityIDFromContext - returns the activity ID of a context, if any
+func ActivityIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(activityIDKey{}).(string)
+	return id
+}
+
 // Resource Request
 type Request struct {
-	rLink    string
-	rId      string
-	rType    string
-	rContext context.Context
+	rLink      string
+	rId        string
+	rType      string
+	rContext   context.Context
+	rOperation string
 	*http.Request
 }
 
 // Return new resource request with type and id
 func ResourceRequest(link string, req *http.Request) *Request {
 	rLink, rId, rType := parse(link)
-	return &Request{rLink, rId, rType, nil, req}
+	return &Request{rLink, rId, rType, nil, "", req}
+}
+
+// Resource names by resource type, singular and plural
+var resourceNames = map[string][2]string{
+	"dbs":         {"Database", "Databases"},
+	"colls":       {"Collection", "Collections"},
+	"docs":        {"Document", "Documents"},
+	"sprocs":      {"StoredProcedure", "StoredProcedures"},
+	"udfs":        {"UserDefinedFunction", "UserDefinedFunctions"},
+	"triggers":    {"Trigger", "Triggers"},
+	"users":       {"User", "Users"},
+	"permissions": {"Permission", "Permissions"},
+	"attachments": {"Attachment", "Attachments"},
+	"pkranges":    {"PartitionKeyRange", "PartitionKeyRanges"},
+}
+
+// Return the resource type of the request eg. "docs"
+func (req *Request) ResourceType() string {
+	return req.rType
+}
+
+// Return the id of the database the request targets, if any
+func (req *Request) Database() string {
+	return req.pathSegment("dbs")
+}
+
+// Return the id of the collection the request targets, if any
+func (req *Request) Collection() string {
+	return req.pathSegment("colls")
+}
+
+// Return the path segment following the passed resource type
+func (req *Request) pathSegment(rType string) string {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == rType {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
+
+// Return the logical operation of the request named after the client method eg. "QueryDocuments"
+func (req *Request) Operation() string {
+	if req.rOperation != "" {
+		return req.rOperation
+	}
+	names, ok := resourceNames[req.rType]
+	if !ok {
+		names = [2]string{req.rType, req.rType}
+	}
+	feed := strings.HasSuffix(strings.TrimSuffix(req.URL.Path, "/"), "/"+req.rType)
+	switch {
+	case req.Header.Get(HeaderIsQuery) == "true":
+		return "Query" + names[1]
+	case req.Method == http.MethodGet && feed:
+		return "Read" + names[1]
+	case req.Method == http.MethodGet:
+		return "Read" + names[0]
+	case req.Method == http.MethodPost && !feed:
+		return "Execute" + names[0]
+	case req.Method == http.MethodPost && req.Header.Get(HeaderUpsert) == "true":
+		return "Upsert" + names[0]
+	case req.Method == http.MethodPost:
+		return "Create" + names[0]
+	case req.Method == http.MethodPut:
+		return "Replace" + names[0]
+	case req.Method == http.MethodDelete:
+		return "Delete" + names[0]
+	}
+	return req.Method + names[0]
+}
+
+// Return the requests context or the background context if none was passed
+func (req *Request) context() context.Context {
+	if req.rContext != nil {
+		return req.rContext
+	}
+	return context.Background()
 }
 
 // Add 3 default headers to *Request
 // "x-ms-date", "x-ms-version", "authorization"
 func (req *Request) DefaultHeaders(mKey string) (err error) {
+	req.defaultHeaders()
+	return req.MasterKeyAuth(mKey)
+}
+
+// Add 3 default headers to *Request, authorized with an AAD bearer token
+// "x-ms-date", "x-ms-version", "authorization"
+func (req *Request) TokenHeaders(token string) {
+	req.defaultHeaders()
+	req.TokenAuth(token)
+}
+
+func (req *Request) defaultHeaders() {
 	req.Header.Add(HeaderXDate, time.Now().UTC().Format("Mon, 02 Jan 2006 15:04:05 GMT"))
 	req.Header.Add(HeaderVersion, SupportedAPIVersion)
 	req.Header.Add(HeaderUserAgent, UserAgent)
+}
+
+// Set the authorization header to the passed AAD bearer token
+func (req *Request) TokenAuth(token string) {
+	aadToken := "aad"
+	tokenVersion := "1.0"
+	req.Header.Set(HeaderAuth, url.QueryEscape("type="+aadToken+"&ver="+tokenVersion+"&sig="+token))
+}
 
-	// Auth
+// Set the authorization header signed with the passed master key
+func (req *Request) MasterKeyAuth(mKey string) (err error) {
 	parts := req.Method + "\n" +
 		req.rType + "\n" +
 		req.rLink + "\n" +
@@ -62,10 +191,22 @@ func (req *Request) DefaultHeaders(mKey string) (err error) {
 
 	masterToken := "master"
 	tokenVersion := "1.0"
-	req.Header.Add(HeaderAuth, url.QueryEscape("type="+masterToken+"&ver="+tokenVersion+"&sig="+sign))
+	req.Header.Set(HeaderAuth, url.QueryEscape("type="+masterToken+"&ver="+tokenVersion+"&sig="+sign))
 	return
 }
 
+// Set the activity ID header from the requests context or a new ID, unless it is already set
+func (req *Request) ActivityIDHeaders() {
+	if req.Header.Get(HeaderActivityID) != "" {
+		return
+	}
+	id := ActivityIDFromContext(req.rContext)
+	if id == "" {
+		id = genId()
+	}
+	req.Header.Set(HeaderActivityID, id)
+}
+
 // Add headers for query request
 func (req *Request) QueryHeaders(len int) {
 	req.Header.Add(HeaderContentType, "application/query+json")
@@ -2,6 +2,7 @@ package gocosmosdb
 
 import (
 	"bytes"
+	"context"
 	"net/http"
 	"testing"
 
@@ -26,6 +27,45 @@ func TestDefaultHeaders(t *testing.T) {
 	assert.NotEqual(req.Header.Get(HeaderVersion), "")
 }
 
+func TestTokenHeaders(t *testing.T) {
+	r, _ := http.NewRequest("GET", "link", &bytes.Buffer{})
+	req := ResourceRequest("/dbs/b5NCAA==/", r)
+	req.TokenHeaders("token")
+
+	assert := assert.New(t)
+	assert.Equal("type%3Daad%26ver%3D1.0%26sig%3Dtoken", req.Header.Get(HeaderAuth))
+	assert.NotEqual(req.Header.Get(HeaderXDate), "")
+	assert.NotEqual(req.Header.Get(HeaderVersion), "")
+}
+
+func TestActivityIDHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	// generated
+	r, _ := http.NewRequest("GET", "link", &bytes.Buffer{})
+	req := ResourceRequest("/dbs/b5NCAA==/", r)
+	req.ActivityIDHeaders()
+	assert.Len(req.Header.Get(HeaderActivityID), 36)
+
+	// from the context
+	ctx := WithActivityID(context.Background(), "from-context")
+	assert.Equal("from-context", ActivityIDFromContext(ctx))
+	r, _ = http.NewRequest("GET", "link", &bytes.Buffer{})
+	req = ResourceRequest("/dbs/b5NCAA==/", r)
+	req.rContext = ctx
+	req.ActivityIDHeaders()
+	assert.Equal("from-context", req.Header.Get(HeaderActivityID))
+
+	// explicitly set
+	req.Header.Set(HeaderActivityID, "explicit")
+	req.ActivityIDHeaders()
+	assert.Equal("explicit", req.Header.Get(HeaderActivityID))
+
+	// generated for the context
+	assert.Len(ActivityIDFromContext(WithActivityID(context.Background(), "")), 36)
+	assert.Equal("", ActivityIDFromContext(context.Background()))
+}
+
 // Get link and return resource Id and Type
 // /dbs	Feed of databases under a database account - 1 - 3
 // /dbs/{dbName}	Database with an id matching the value {dbName} - 2 - 4
@@ -175,3 +215,26 @@ func TestParseLink(t *testing.T) {
 	assert.Equal("mydoc", rId)
 	assert.Equal("permissions", rType)
 }
+
+func TestRequestOperation(t *testing.T) {
+	assert := assert.New(t)
+	newReq := func(method, link string) *Request {
+		r, _ := http.NewRequest(method, "https://example.com/"+link, &bytes.Buffer{})
+		return ResourceRequest(link, r)
+	}
+	req := newReq("POST", "dbs/db/colls/coll/docs")
+	req.QueryHeaders(0)
+	assert.Equal("QueryDocuments", req.Operation())
+	assert.Equal("coll", req.Collection())
+	assert.Equal("docs", req.ResourceType())
+	req = newReq("POST", "dbs/db/colls/coll/docs")
+	req.Header.Set(HeaderUpsert, "true")
+	assert.Equal("UpsertDocument", req.Operation())
+	assert.Equal("CreateDocument", newReq("POST", "dbs/db/colls/coll/docs").Operation())
+	assert.Equal("ReadCollections", newReq("GET", "dbs/db/colls").Operation())
+	assert.Equal("ReadDatabase", newReq("GET", "dbs/db").Operation())
+	assert.Equal("ExecuteStoredProcedure", newReq("POST", "dbs/db/colls/coll/sprocs/sp").Operation())
+	assert.Equal("ReplaceDocument", newReq("PUT", "dbs/db/colls/coll/docs/1").Operation())
+	assert.Equal("DeleteUser", newReq("DELETE", "dbs/db/users/u").Operation())
+	assert.Equal("", newReq("GET", "dbs/db").Collection())
+}
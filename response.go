@@ -8,7 +8,8 @@ import (
 )
 
 type Response struct {
-	Header http.Header
+	Header      http.Header
+	Diagnostics *Diagnostics
 }
 
 // Continuation - returns continuation token for paged request.
@@ -23,6 +24,17 @@ func (r *Response) SessionToken() string {
 	return r.Header.Get(HeaderSessionToken)
 }
 
+// ActivityID - returns the activity ID echoed by the server, or the one the request was sent with.
+func (r *Response) ActivityID() string {
+	if id := r.Header.Get(HeaderActivityID); id != "" {
+		return id
+	}
+	if r.Diagnostics != nil {
+		return r.Diagnostics.ActivityID
+	}
+	return ""
+}
+
 // GetRUs - returns a responses RUs
 func (r *Response) GetRUs() (float64, error) {
 	// x-ms-request-charge: 604.42
@@ -89,3 +89,12 @@ func TestGetRUs(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(float64(604.42), rus)
 }
+
+func TestActivityID(t *testing.T) {
+	assert := assert.New(t)
+
+	resp := &Response{Header: http.Header{}, Diagnostics: &Diagnostics{ActivityID: "sent"}}
+	assert.Equal("sent", resp.ActivityID())
+	resp.Header.Set(HeaderActivityID, "echoed")
+	assert.Equal("echoed", resp.ActivityID())
+}
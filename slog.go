@@ -0,0 +1,41 @@
+package gocosmosdb
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// slogLogger - adapts a *slog.Logger to Logger
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger - returns a Logger writing to the passed *slog.Logger, or slog.Default() when it is nil
+//	client := gocosmosdb.New(url, gocosmosdb.Config{Debug: true}, gocosmosdb.NewSlogLogger(slog.Default()))
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &slogLogger{logger}
+}
+
+func (l *slogLogger) Infof(format string, v ...interface{}) {
+	l.log(slog.LevelInfo, format, v...)
+}
+
+func (l *slogLogger) Warningf(format string, v ...interface{}) {
+	l.log(slog.LevelWarn, format, v...)
+}
+
+func (l *slogLogger) Errorf(format string, v ...interface{}) {
+	l.log(slog.LevelError, format, v...)
+}
+
+func (l *slogLogger) log(level slog.Level, format string, v ...interface{}) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+	l.logger.Log(ctx, level, fmt.Sprintf(format, v...))
+}
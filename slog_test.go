@@ -0,0 +1,24 @@
+//go:build go1.21
+
+package gocosmosdb
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlogLogger(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	l := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	l.Infof("hidden %d", 1)
+	l.Warningf("careful %d", 2)
+	l.Errorf("broken %d", 3)
+	assert.NotContains(buf.String(), "hidden")
+	assert.Contains(buf.String(), `level=WARN msg="careful 2"`)
+	assert.Contains(buf.String(), `level=ERROR msg="broken 3"`)
+	assert.NotNil(NewSlogLogger(nil))
+}
@@ -0,0 +1,309 @@
+package gocosmosdb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// maxPendingQueries - the most queries with pages still to read a SlowQueryLog tracks, the oldest is finished when
+// another one starts
+const maxPendingQueries = 1000
+
+// defaultSlowQueryIdle - how long a query waits for its next page before it is finished, unless Idle is set
+const defaultSlowQueryIdle = time.Minute
+
+// SlowQueryLog - collects the query metrics of every page of a query and logs the queries passing a threshold once
+// their last page is read. Pages are grouped by their activity ID and query text and parameters, queries read with
+// NewPagableQuery, NewCrossPartitionQuery, Query or the QueryAll helpers share an activity ID across pages. A
+// cross-partition query is finished once it is done or closed. A query whose next page is not read within Idle is
+// finished when the next query page is recorded, or by Flush. A query is slow when it passes either threshold, every
+// query is when both are 0.
+//	client := gocosmosdb.New(url, gocosmosdb.Config{
+//		MasterKey:    key,
+//		SlowQueryLog: &gocosmosdb.SlowQueryLog{RequestCharge: 100, Latency: time.Second, RedactParameters: true},
+//	}, log)
+type SlowQueryLog struct {
+	RequestCharge    float64            // queries consuming more request units across their pages are slow
+	Latency          time.Duration      // queries whose pages take longer in total are slow
+	RedactParameters bool               // parameter values are logged as Redacted
+	Handler          func(q *SlowQuery) // when set, called with every slow query instead of logging it
+	Idle             time.Duration      // how long a query waits for its next page before it is finished, defaults to a minute
+	mu               sync.Mutex
+	pending          map[string]*SlowQuery
+	log              Logger
+}
+
+// SlowQuery - a query and its metrics aggregated across its pages
+type SlowQuery struct {
+	Query         string           `json:"query"` // the query text with its literals replaced by ?
+	Parameters    []QueryParameter `json:"parameters,omitempty"`
+	Link          string           `json:"link"`
+	ActivityID    string           `json:"activityId,omitempty"`
+	Pages         int              `json:"pages"`
+	RequestCharge float64          `json:"requestCharge"`
+	Latency       time.Duration    `json:"latency"`
+	Metrics       Metrics          `json:"metrics"`
+	Error         string           `json:"error,omitempty"`
+	key           string
+	start         time.Time
+	last          time.Time
+	indexHits     float64
+}
+
+// String - formats the slow query as a single log line
+func (q *SlowQuery) String() string {
+	params, _ := json.Marshal(q.Parameters)
+	line := fmt.Sprintf("slow query: ru=%.2f latency=%s pages=%d retrievedDocuments=%d outputDocuments=%d indexUtilization=%.2f activityId=%s link=%s query=%q parameters=%s",
+		q.RequestCharge, q.Latency, q.Pages, q.Metrics.RetrievedDocumentCount, q.Metrics.OutputDocumentCount,
+		q.Metrics.IndexUtilizationRatio, q.ActivityID, q.Link, q.Query, params)
+	if q.Error != "" {
+		line += " error=" + strconv.Quote(q.Error)
+	}
+	return line
+}
+
+// interceptor - returns an interceptor requesting the metrics of every query page and recording them
+func (s *SlowQueryLog) interceptor(log Logger) Interceptor {
+	return func(r *Request, next Invoker) (*http.Response, error) {
+		if r.Header.Get(HeaderIsQuery) != "true" || r.Header.Get(HeaderIsQueryPlan) == "true" {
+			return next(r)
+		}
+		body, err := readBody(r.Request)
+		if err != nil {
+			return nil, err
+		}
+		r.Header.Set(HeaderPopulateQueryMetrics, "true")
+		start := time.Now()
+		resp, err := next(r)
+		s.record(log, r, body, start, resp, err)
+		return resp, err
+	}
+}
+
+// record - adds a page to its query, the query is finished with its last page or an error
+func (s *SlowQueryLog) record(log Logger, r *Request, body []byte, start time.Time, resp *http.Response, err error) {
+	now := time.Now()
+	query := QueryWithParameters{}
+	json.Unmarshal(body, &query)
+	id := r.Header.Get(HeaderActivityID)
+	// queries sharing the activity ID of a context are told apart by their text and parameters
+	key := id + "/" + queryHash(r.rLink, &query)
+	s.mu.Lock()
+	if log != nil {
+		s.log = log
+	}
+	if s.pending == nil {
+		s.pending = map[string]*SlowQuery{}
+	}
+	q, ok := s.pending[key]
+	if !ok {
+		q = s.newQuery(r, &query, start)
+		q.key = key
+	}
+	q.Pages++
+	q.Latency += now.Sub(start)
+	q.last = now
+	// the last page of a partition key range is not the last page of a cross-partition query, it ends when the query
+	// is done or closed
+	rangePage := r.Header.Get(HeaderPartitionKeyRangeID) != ""
+	done := id == ""
+	switch {
+	case err != nil:
+		q.Error, done = err.Error(), true
+	case resp.StatusCode >= http.StatusBadRequest:
+		q.Error, done = resp.Status, true
+	default:
+		q.add(&Response{Header: resp.Header})
+		done = done || (!rangePage && resp.Header.Get(HeaderContinuation) == "")
+	}
+	delete(s.pending, key)
+	finished := s.expired(now)
+	if done {
+		finished = append(finished, q)
+	} else {
+		if len(s.pending) >= maxPendingQueries {
+			finished = append(finished, s.oldest())
+		}
+		s.pending[key] = q
+	}
+	s.mu.Unlock()
+	for _, q := range finished {
+		s.finish(q)
+	}
+}
+
+// newQuery - starts a query from the body of its first page
+func (s *SlowQueryLog) newQuery(r *Request, query *QueryWithParameters, start time.Time) *SlowQuery {
+	q := &SlowQuery{
+		Query:      normalizeQuery(query.Query),
+		Link:       r.rLink,
+		ActivityID: r.Header.Get(HeaderActivityID),
+		start:      start,
+	}
+	for _, p := range query.Parameters {
+		if s.RedactParameters {
+			p.Value = Redacted
+		}
+		q.Parameters = append(q.Parameters, p)
+	}
+	return q
+}
+
+// expired - removes and returns the pending queries whose last page was read longer than Idle ago
+func (s *SlowQueryLog) expired(now time.Time) []*SlowQuery {
+	idle := s.Idle
+	if idle <= 0 {
+		idle = defaultSlowQueryIdle
+	}
+	expired := []*SlowQuery{}
+	for key, q := range s.pending {
+		if now.Sub(q.last) > idle {
+			delete(s.pending, key)
+			expired = append(expired, q)
+		}
+	}
+	return expired
+}
+
+// oldest - removes and returns the pending query started first
+func (s *SlowQueryLog) oldest() *SlowQuery {
+	var oldest *SlowQuery
+	for _, q := range s.pending {
+		if oldest == nil || q.start.Before(oldest.start) {
+			oldest = q
+		}
+	}
+	delete(s.pending, oldest.key)
+	return oldest
+}
+
+// complete - finishes the pending queries of an activity, eg. the pages of a cross-partition query once it is done
+func (s *SlowQueryLog) complete(activityID string) {
+	s.mu.Lock()
+	finished := []*SlowQuery{}
+	for key, q := range s.pending {
+		if q.ActivityID == activityID {
+			delete(s.pending, key)
+			finished = append(finished, q)
+		}
+	}
+	s.mu.Unlock()
+	for _, q := range finished {
+		s.finish(q)
+	}
+}
+
+// add - adds the request charge and query metrics of a page
+func (q *SlowQuery) add(resp *Response) {
+	if charge, err := resp.GetRUs(); err == nil {
+		q.RequestCharge += charge
+	}
+	m, err := resp.GetQueryMetrics()
+	if err != nil {
+		return
+	}
+	t := &q.Metrics
+	t.TotalExecutionTimeInMs += m.TotalExecutionTimeInMs
+	t.QueryCompileTimeInMs += m.QueryCompileTimeInMs
+	t.QueryLogicalPlanBuildTimeInMs += m.QueryLogicalPlanBuildTimeInMs
+	t.QueryPhysicalPlanBuildTimeInMs += m.QueryPhysicalPlanBuildTimeInMs
+	t.QueryOptimizationTimeInMs += m.QueryOptimizationTimeInMs
+	t.VMExecutionTimeInMs += m.VMExecutionTimeInMs
+	t.IndexLookupTimeInMs += m.IndexLookupTimeInMs
+	t.DocumentLoadTimeInMs += m.DocumentLoadTimeInMs
+	t.SystemFunctionExecuteTimeInMs += m.SystemFunctionExecuteTimeInMs
+	t.UserFunctionExecuteTimeInMs += m.UserFunctionExecuteTimeInMs
+	t.RetrievedDocumentCount += m.RetrievedDocumentCount
+	t.RetrievedDocumentSize += m.RetrievedDocumentSize
+	t.OutputDocumentCount += m.OutputDocumentCount
+	t.WriteOutputTimeInMs += m.WriteOutputTimeInMs
+	t.RequestCharge += m.RequestCharge
+	// the utilization of the query is the utilization of every page weighted by the documents it retrieved
+	q.indexHits += m.IndexUtilizationRatio * float64(m.RetrievedDocumentCount)
+	if t.RetrievedDocumentCount > 0 {
+		t.IndexUtilizationRatio = q.indexHits / float64(t.RetrievedDocumentCount)
+	}
+}
+
+// finish - logs a query passing a threshold
+func (s *SlowQueryLog) finish(q *SlowQuery) {
+	if !s.slow(q) {
+		return
+	}
+	if s.Handler != nil {
+		s.Handler(q)
+		return
+	}
+	s.mu.Lock()
+	log := s.log
+	s.mu.Unlock()
+	if log != nil {
+		log.Warningf("%s", q)
+	}
+}
+
+// slow - whether a query passes a threshold
+func (s *SlowQueryLog) slow(q *SlowQuery) bool {
+	if s.RequestCharge <= 0 && s.Latency <= 0 {
+		return true
+	}
+	return (s.RequestCharge > 0 && q.RequestCharge > s.RequestCharge) || (s.Latency > 0 && q.Latency > s.Latency)
+}
+
+// Flush - finishes the queries whose last page has not been read, eg. abandoned pagable queries
+func (s *SlowQueryLog) Flush() {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	for _, q := range pending {
+		s.finish(q)
+	}
+}
+
+// normalizeQuery - collapses the whitespace of a query and replaces its string and number literals with ? so
+// queries differing only in literals read the same
+func normalizeQuery(query string) string {
+	var b strings.Builder
+	runes := []rune(query)
+	var prev rune
+	for i := 0; i < len(runes); i++ {
+		ch := runes[i]
+		if unicode.IsSpace(ch) {
+			if prev != 0 && prev != ' ' {
+				prev = ' '
+				b.WriteRune(prev)
+			}
+			continue
+		}
+		switch {
+		case ch == '\'' || ch == '"':
+			// skip to the closing quote, a backslash escapes the next character
+			for i++; i < len(runes) && runes[i] != ch; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+			ch = '?'
+		case unicode.IsDigit(ch) && !identifierRune(prev):
+			for i+1 < len(runes) && (unicode.IsDigit(runes[i+1]) || runes[i+1] == '.') {
+				i++
+			}
+			ch = '?'
+		}
+		prev = ch
+		b.WriteRune(ch)
+	}
+	return strings.TrimSuffix(b.String(), " ")
+}
+
+// identifierRune - whether r can be part of an identifier or parameter name
+func identifierRune(r rune) bool {
+	return r == '_' || r == '@' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
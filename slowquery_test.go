@@ -0,0 +1,165 @@
+package gocosmosdb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// metricsServer - serves two pages of a query with their query metrics, the first page retrieves 10 documents with a
+// full index utilization and the second 30 with none
+func metricsServer(populated *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*populated = append(*populated, r.Header.Get(HeaderPopulateQueryMetrics))
+		w.Header().Set(HeaderRequestCharge, "2.5")
+		if r.Header.Get(HeaderContinuation) == "" {
+			w.Header().Set(HeaderContinuation, "page2")
+			w.Header().Set(HeaderQueryMetrics, "totalExecutionTimeInMs=1.5;retrievedDocumentCount=10;outputDocumentCount=1;indexUtilizationRatio=1.00")
+			fmt.Fprint(w, `{"Documents": [{"id": "1"}]}`)
+			return
+		}
+		w.Header().Set(HeaderQueryMetrics, "totalExecutionTimeInMs=2.5;retrievedDocumentCount=30;outputDocumentCount=1;indexUtilizationRatio=0.00")
+		fmt.Fprint(w, `{"Documents": [{"id": "2"}]}`)
+	}))
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	assert := assert.New(t)
+	populated := []string{}
+	s := metricsServer(&populated)
+	defer s.Close()
+	logs := &recordingLogger{}
+	slow := &SlowQueryLog{RequestCharge: 4, RedactParameters: true}
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", SlowQueryLog: slow}, logs)
+	docs := []Document{}
+	query := &QueryWithParameters{
+		Query:      "SELECT * FROM c WHERE c.ssn = @ssn AND c.age > 21",
+		Parameters: []QueryParameter{{"@ssn", "123-45"}},
+	}
+	pg := client.NewPagableQuery("dbs/db/colls/coll/", query, 1, &docs)
+	assert.Nil(pg.Next())
+	assert.Len(logs.lines, 0)
+	assert.Nil(pg.Next())
+	assert.True(pg.Done())
+	assert.Equal([]string{"true", "true"}, populated)
+	assert.Len(logs.lines, 1)
+	line := logs.lines[0]
+	assert.Contains(line, "ru=5.00 ")
+	assert.Contains(line, "pages=2 ")
+	assert.Contains(line, "retrievedDocuments=40 outputDocuments=2 indexUtilization=0.25 ")
+	assert.Contains(line, `query="SELECT * FROM c WHERE c.ssn = @ssn AND c.age > ?"`)
+	assert.Contains(line, `parameters=[{"name":"@ssn","value":"REDACTED"}]`)
+	assert.Contains(line, "link=dbs/db/colls/coll/docs")
+	assert.NotContains(line, "123-45")
+}
+
+func TestSlowQueryLogThresholds(t *testing.T) {
+	assert := assert.New(t)
+	populated := []string{}
+	s := metricsServer(&populated)
+	defer s.Close()
+	queries := []*SlowQuery{}
+	slow := &SlowQueryLog{RequestCharge: 10, Handler: func(q *SlowQuery) {
+		queries = append(queries, q)
+	}}
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", SlowQueryLog: slow}, log)
+	docs := []Document{}
+	_, err := client.QueryAllDocuments("dbs/db/colls/coll/", "SELECT * FROM c", &docs, 0)
+	assert.Nil(err)
+	assert.Len(queries, 0)
+
+	// every query is slow without thresholds, an abandoned query is finished by Flush
+	slow.RequestCharge = 0
+	_, err = client.QueryDocuments("dbs/db/colls/coll/", "SELECT * FROM c WHERE c.name = 'ann'", &docs)
+	assert.Nil(err)
+	assert.Len(queries, 0)
+	slow.Flush()
+	assert.Len(queries, 1)
+	assert.Equal("SELECT * FROM c WHERE c.name = ?", queries[0].Query)
+	assert.Equal(1, queries[0].Pages)
+	assert.Equal(1.5, queries[0].Metrics.TotalExecutionTimeInMs)
+
+	// metadata requests are not queries
+	_, err = client.ReadDocument("dbs/db/colls/coll/docs/1", &Document{})
+	assert.Nil(err)
+	assert.Equal("", populated[len(populated)-1])
+}
+
+func TestSlowQueryLogCrossPartition(t *testing.T) {
+	assert := assert.New(t)
+	s := newPartitionedServer(orderByPlan, orderByResults())
+	defer s.Close()
+	queries := []*SlowQuery{}
+	slow := &SlowQueryLog{Handler: func(q *SlowQuery) {
+		queries = append(queries, q)
+	}}
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", SlowQueryLog: slow}, log)
+	query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().OrderBy("c.name").Build(), 2)
+	for !query.Done() {
+		assert.Nil(query.Next(&[]Document{}))
+		if !query.Done() {
+			// a range read to its end does not end the query
+			assert.Len(queries, 0)
+		}
+	}
+	// the 5 pages of the 3 ranges are one query
+	assert.Len(queries, 1)
+	assert.Equal(5, queries[0].Pages)
+	assert.Equal(5.0, queries[0].RequestCharge)
+	assert.Contains(queries[0].Query, "ORDER BY c.name")
+}
+
+func TestSlowQueryLogSharedActivityID(t *testing.T) {
+	assert := assert.New(t)
+	populated := []string{}
+	s := metricsServer(&populated)
+	defer s.Close()
+	queries := []*SlowQuery{}
+	slow := &SlowQueryLog{Idle: 20 * time.Millisecond, Handler: func(q *SlowQuery) {
+		queries = append(queries, q)
+	}}
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", SlowQueryLog: slow}, log)
+	ctx := WithActivityID(context.Background(), "shared")
+	docs := []Document{}
+
+	// the first pages of two queries sharing an activity ID are two queries
+	_, err := client.QueryDocuments("dbs/db/colls/coll/", "SELECT * FROM c WHERE c.a = 1", &docs, WithContext(ctx))
+	assert.Nil(err)
+	_, err = client.QueryDocuments("dbs/db/colls/coll/", "SELECT * FROM c WHERE c.b = 1", &docs, WithContext(ctx))
+	assert.Nil(err)
+	assert.Len(queries, 0)
+	slow.Flush()
+	assert.Len(queries, 2)
+	assert.NotEqual(queries[0].Query, queries[1].Query)
+	assert.Equal(1, queries[0].Pages)
+	assert.Equal(1, queries[1].Pages)
+
+	// a query whose next page is not read is finished once it is idle
+	queries = nil
+	_, err = client.QueryDocuments("dbs/db/colls/coll/", "SELECT * FROM c WHERE c.a = 1", &docs, WithContext(ctx))
+	assert.Nil(err)
+	time.Sleep(30 * time.Millisecond)
+	_, err = client.QueryDocuments("dbs/db/colls/coll/", "SELECT * FROM c WHERE c.b = 1", &docs, WithContext(ctx))
+	assert.Nil(err)
+	assert.Len(queries, 1)
+	assert.Equal("SELECT * FROM c WHERE c.a = ?", queries[0].Query)
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	assert := assert.New(t)
+	for query, normalized := range map[string]string{
+		"SELECT *\n\tFROM c  WHERE c.a = 'x'":               "SELECT * FROM c WHERE c.a = ?",
+		`SELECT TOP 10 c.id FROM c WHERE c.n = "a\"b" `:     "SELECT TOP ? c.id FROM c WHERE c.n = ?",
+		"SELECT c.items[0] FROM c WHERE c.v1 > -1.5 OR c.x": "SELECT c.items[?] FROM c WHERE c.v1 > -? OR c.x",
+		"SELECT * FROM c WHERE c.p = @p0 OFFSET 5 LIMIT 10": "SELECT * FROM c WHERE c.p = @p0 OFFSET ? LIMIT ?",
+	} {
+		assert.Equal(normalized, normalizeQuery(query), query)
+	}
+	assert.False(strings.Contains(normalizeQuery("  "), " "))
+}
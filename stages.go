@@ -0,0 +1,208 @@
+package gocosmosdb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// maxDistinctContinuation - the most result hashes the continuation token of an unordered DISTINCT query holds,
+// about 45 bytes each
+const maxDistinctContinuation = 1000
+
+// distinctState - the results a distinct stage has returned, the hash of the last one for an ordered DISTINCT
+// whose duplicates are adjacent, or the hashes of all of them
+type distinctState struct {
+	Last string   `json:"last,omitempty"`
+	Seen []string `json:"seen,omitempty"`
+}
+
+// distinctStage - drops the results equal to a result already returned
+type distinctStage struct {
+	source  querySource
+	ordered bool
+	last    string
+	seen    map[string]bool
+	pending string
+}
+
+// newDistinctStage - creates a distinct stage resuming from state
+func newDistinctStage(source querySource, ordered bool, state *distinctState) *distinctStage {
+	d := &distinctStage{source: source, ordered: ordered, last: state.Last, seen: map[string]bool{}}
+	for _, h := range state.Seen {
+		d.seen[h] = true
+	}
+	return d
+}
+
+// duplicate - whether a result with the hash was returned already
+func (d *distinctStage) duplicate(h string) bool {
+	if d.ordered {
+		return h == d.last
+	}
+	return d.seen[h]
+}
+
+func (d *distinctStage) peek() (json.RawMessage, bool, error) {
+	for {
+		raw, ok, err := d.source.peek()
+		if !ok || err != nil {
+			return nil, ok, err
+		}
+		h, err := hashResult(raw)
+		if err != nil {
+			return nil, false, err
+		}
+		if !d.duplicate(h) {
+			d.pending = h
+			return raw, true, nil
+		}
+		if _, _, err = d.source.next(); err != nil {
+			return nil, false, err
+		}
+	}
+}
+
+func (d *distinctStage) next() (json.RawMessage, bool, error) {
+	raw, ok, err := d.peek()
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	if _, _, err = d.source.next(); err != nil {
+		return nil, false, err
+	}
+	if d.ordered {
+		d.last = d.pending
+	} else {
+		d.seen[d.pending] = true
+	}
+	return raw, true, nil
+}
+
+func (d *distinctStage) state(token *crossPartitionToken) {
+	d.source.state(token)
+	state := &distinctState{Last: d.last}
+	if !d.ordered {
+		for h := range d.seen {
+			state.Seen = append(state.Seen, h)
+		}
+	}
+	token.Distinct = state
+}
+
+// hashResult - hashes the canonical JSON of a result, so equal values with different formatting hash the same
+func hashResult(raw json.RawMessage) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "", err
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:16]), nil
+}
+
+// skipStage - drops the first results, for OFFSET
+type skipStage struct {
+	source querySource
+	skip   *int
+}
+
+func (s *skipStage) peek() (json.RawMessage, bool, error) {
+	for *s.skip > 0 {
+		_, ok, err := s.source.next()
+		if !ok || err != nil {
+			return nil, ok, err
+		}
+		*s.skip--
+	}
+	return s.source.peek()
+}
+
+func (s *skipStage) next() (json.RawMessage, bool, error) {
+	if _, ok, err := s.peek(); !ok || err != nil {
+		return nil, ok, err
+	}
+	return s.source.next()
+}
+
+func (s *skipStage) state(token *crossPartitionToken) {
+	s.source.state(token)
+	token.Offset = s.skip
+}
+
+// takeStage - returns at most a number of results, for LIMIT and TOP
+type takeStage struct {
+	source querySource
+	take   *int
+	set    func(token *crossPartitionToken, take *int)
+}
+
+func (t *takeStage) peek() (json.RawMessage, bool, error) {
+	if *t.take <= 0 {
+		return nil, false, nil
+	}
+	return t.source.peek()
+}
+
+func (t *takeStage) next() (json.RawMessage, bool, error) {
+	if *t.take <= 0 {
+		return nil, false, nil
+	}
+	raw, ok, err := t.source.next()
+	if ok {
+		*t.take--
+	}
+	return raw, ok, err
+}
+
+func (t *takeStage) state(token *crossPartitionToken) {
+	t.source.state(token)
+	t.set(token, t.take)
+}
+
+// pipeline - wraps the results of the partition key ranges in the stages the query plan requires, in the order
+// of the official SDKs: aggregates, DISTINCT, OFFSET, LIMIT and TOP
+func (qi queryInfo) pipeline(source querySource, token *crossPartitionToken) querySource {
+	switch {
+	case len(qi.GroupByExpressions) > 0 || len(qi.GroupByAliasToAggregateType) > 0:
+		source = newBlockingStage(source, token, qi.groupBy)
+	case len(qi.Aggregates) > 0:
+		source = newBlockingStage(source, token, qi.aggregate)
+	}
+	if token.Distinct != nil {
+		source = newDistinctStage(source, qi.DistinctType == "Ordered", token.Distinct)
+	}
+	if token.Offset != nil {
+		source = &skipStage{source: source, skip: copyInt(token.Offset)}
+	}
+	if token.Limit != nil {
+		source = &takeStage{source: source, take: copyInt(token.Limit), set: func(token *crossPartitionToken, take *int) {
+			token.Limit = copyInt(take)
+		}}
+	}
+	if token.Top != nil {
+		source = &takeStage{source: source, take: copyInt(token.Top), set: func(token *crossPartitionToken, take *int) {
+			token.Top = copyInt(take)
+		}}
+	}
+	return source
+}
+
+// initialize - sets the initial state of the stages of the query plan in a new continuation token
+func (qi queryInfo) initialize(token *crossPartitionToken) {
+	if qi.DistinctType != "" && qi.DistinctType != "None" {
+		token.Distinct = &distinctState{}
+	}
+	token.Offset, token.Limit, token.Top = copyInt(qi.Offset), copyInt(qi.Limit), copyInt(qi.Top)
+}
+
+func copyInt(i *int) *int {
+	if i == nil {
+		return nil
+	}
+	c := *i
+	return &c
+}
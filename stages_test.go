@@ -0,0 +1,125 @@
+package gocosmosdb
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pagesOf - reads every page of a query, resuming each page from the continuation token of the previous one
+func pagesOf(t *testing.T, client *CosmosDB, pageSize int) [][]string {
+	pages := [][]string{}
+	continuation := ""
+	for {
+		query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().Build(), pageSize).SetContinuation(continuation)
+		page := []string{}
+		assert.Nil(t, query.Next(&page))
+		pages = append(pages, page)
+		var err error
+		continuation, err = query.Continuation()
+		assert.Nil(t, err)
+		if continuation == "" {
+			return pages
+		}
+	}
+}
+
+func TestCrossPartitionUnorderedDistinct(t *testing.T) {
+	assert := assert.New(t)
+	plan := `{"queryInfo": {"distinctType": "Unordered", "hasSelectValue": true, "rewrittenQuery": "SELECT DISTINCT VALUE c.team FROM c"}}`
+	s := newPartitionedServer(plan, map[string][]string{
+		"0": {`"red"`, `"blue"`},
+		"1": {`"blue"`, `"green"`, `"red"`},
+		"2": {`"green"`, `"gold"`},
+	})
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	assert.Equal([][]string{{"red", "blue"}, {"green", "gold"}}, pagesOf(t, client, 2))
+}
+
+func TestCrossPartitionUnorderedDistinctLimit(t *testing.T) {
+	assert := assert.New(t)
+	plan := `{"queryInfo": {"distinctType": "Unordered", "hasSelectValue": true, "rewrittenQuery": "SELECT DISTINCT VALUE c.n FROM c"}}`
+	results := []string{}
+	for i := 0; i <= maxDistinctContinuation+1; i++ {
+		results = append(results, strconv.Itoa(i), strconv.Itoa(i))
+	}
+	s := newPartitionedServer(plan, map[string][]string{"0": results})
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	query := client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().Build(), maxDistinctContinuation)
+	page := []int{}
+	assert.Nil(query.Next(&page))
+	assert.Len(page, maxDistinctContinuation)
+	_, err := query.Continuation()
+	assert.Nil(err)
+
+	// every result is returned, only the continuation token is limited
+	query = client.NewCrossPartitionQuery("dbs/db/colls/coll/", Select().Build(), maxDistinctContinuation+1)
+	assert.Nil(query.Next(&page))
+	assert.Len(page, maxDistinctContinuation+1)
+	_, err = query.Continuation()
+	assert.True(IsValidationError(err))
+	assert.Contains(err.Error(), "at most 1000 results")
+	assert.Nil(query.Next(&page))
+	assert.Equal([]int{maxDistinctContinuation + 1}, page)
+	assert.True(query.Done())
+}
+
+func TestCrossPartitionOrderedDistinct(t *testing.T) {
+	assert := assert.New(t)
+	plan := `{"queryInfo": {"distinctType": "Ordered", "orderBy": ["Ascending"], "orderByExpressions": ["c.name"], "hasSelectValue": true,
+		"rewrittenQuery": "SELECT DISTINCT c._rid, [{\"item\": c.name}] AS orderByItems, c.name AS payload FROM c WHERE ({documentdb-formattableorderbyquery-filter}) ORDER BY c.name"}}`
+	doc := func(rid, name string) string {
+		return `{"_rid": "` + rid + `", "orderByItems": [{"item": "` + name + `"}], "payload": "` + name + `"}`
+	}
+	s := newPartitionedServer(plan, map[string][]string{
+		"0": {doc("1", "ann"), doc("2", "bob"), doc("3", "cat")},
+		"1": {doc("4", "ann"), doc("5", "cat")},
+		"2": {doc("6", "bob"), doc("7", "dan")},
+	})
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	assert.Equal([][]string{{"ann", "bob"}, {"cat", "dan"}}, pagesOf(t, client, 2))
+}
+
+func TestCrossPartitionTop(t *testing.T) {
+	assert := assert.New(t)
+	plan := `{"queryInfo": {"distinctType": "None", "top": 3, "hasSelectValue": true, "rewrittenQuery": "SELECT TOP 3 VALUE c.id FROM c"}}`
+	s := newPartitionedServer(plan, map[string][]string{
+		"0": {`"a"`, `"b"`},
+		"1": {`"c"`, `"d"`},
+		"2": {`"e"`},
+	})
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	assert.Equal([][]string{{"a", "b"}, {"c"}}, pagesOf(t, client, 2))
+}
+
+func TestCrossPartitionOffsetLimit(t *testing.T) {
+	assert := assert.New(t)
+	plan := `{"queryInfo": {"distinctType": "None", "offset": 1, "limit": 3, "orderBy": ["Descending"], "orderByExpressions": ["c.name"],
+		"rewrittenQuery": "SELECT c._rid, [{\"item\": c.name}] AS orderByItems, c.id AS payload FROM c WHERE ({documentdb-formattableorderbyquery-filter}) ORDER BY c.name DESC OFFSET 0 LIMIT 4"}}`
+	doc := func(name string) string {
+		return `{"_rid": "` + name + `", "orderByItems": [{"item": "` + name + `"}], "payload": "` + name + `"}`
+	}
+	s := newPartitionedServer(plan, map[string][]string{
+		"0": {doc("f"), doc("c")},
+		"1": {doc("e"), doc("b")},
+		"2": {doc("d"), doc("a")},
+	})
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	assert.Equal([][]string{{"e", "d"}, {"c"}}, pagesOf(t, client, 2))
+}
+
+func TestHashResult(t *testing.T) {
+	assert := assert.New(t)
+	a, err := hashResult([]byte(`{"a": 1, "b": [1.0, "x"]}`))
+	assert.Nil(err)
+	b, _ := hashResult([]byte(`{"b":[1,"x"],"a":1.0}`))
+	c, _ := hashResult([]byte(`{"a": 2, "b": [1, "x"]}`))
+	assert.Equal(a, b)
+	assert.NotEqual(a, c)
+}
@@ -0,0 +1,85 @@
+package gocosmosdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrStopStream - returned by a DocumentFunc to stop streaming a page without an error
+var ErrStopStream = errors.New("stop stream")
+
+// DocumentFunc - called with each document of a streamed page as it is read from the response body
+type DocumentFunc func(doc json.RawMessage) error
+
+// bodyHandler - reads a response body itself instead of having it decoded
+type bodyHandler func(r io.Reader) error
+
+// QueryDocumentsStream - Retrieves a page of documents that satisfy the passed query and hands them to fn one at a time,
+// so the page is never held in memory as a whole. A nil query reads the collection's document feed.
+//	resp, err := client.QueryDocumentsStream(coll, queryWithParams, func(doc json.RawMessage) error {
+//		return json.Unmarshal(doc, &user)
+//	})
+func (c *CosmosDB) QueryDocumentsStream(coll string, query *QueryWithParameters, fn DocumentFunc, opts ...CallOption) (*Response, error) {
+	handler := bodyHandler(func(r io.Reader) error {
+		return streamDocuments(r, "Documents", fn)
+	})
+	if query != nil {
+		return c.client.queryWithParameters(coll+"docs/", query, handler, opts...)
+	}
+	return c.client.read(coll+"docs/", handler, opts...)
+}
+
+// streamDocuments - walks the array under key in a JSON object, passing each element to fn
+func streamDocuments(r io.Reader, key string, fn DocumentFunc) error {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if tok != key {
+			// skip the value of any other field eg. _rid or _count
+			var skip json.RawMessage
+			if err = dec.Decode(&skip); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = expectDelim(dec, '['); err != nil {
+			return err
+		}
+		for dec.More() {
+			var doc json.RawMessage
+			if err = dec.Decode(&doc); err != nil {
+				return err
+			}
+			if err = fn(doc); err != nil {
+				if err == ErrStopStream {
+					return nil
+				}
+				return err
+			}
+		}
+		if err = expectDelim(dec, ']'); err != nil {
+			return err
+		}
+	}
+	return expectDelim(dec, '}')
+}
+
+// expectDelim - reads the next token and checks it is the passed delimiter
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok != delim {
+		return fmt.Errorf("unexpected token %v in response, expected %v", tok, delim)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package gocosmosdb
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamDocuments(t *testing.T) {
+	assert := assert.New(t)
+	body := `{"_rid": "d9RzAJRFKgw=", "Documents": [{"id": "1", "nested": {"a": [1, 2]}}, {"id": "2"}, {"id": "3"}], "_count": 3}`
+
+	ids := []string{}
+	err := streamDocuments(strings.NewReader(body), "Documents", func(doc json.RawMessage) error {
+		d := Document{}
+		if err := json.Unmarshal(doc, &d); err != nil {
+			return err
+		}
+		ids = append(ids, d.Id)
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal([]string{"1", "2", "3"}, ids)
+
+	// stop early
+	count := 0
+	err = streamDocuments(strings.NewReader(body), "Documents", func(doc json.RawMessage) error {
+		count++
+		return ErrStopStream
+	})
+	assert.Nil(err)
+	assert.Equal(1, count)
+
+	// callback errors are returned
+	err = streamDocuments(strings.NewReader(body), "Documents", func(doc json.RawMessage) error {
+		return errors.New("export failed")
+	})
+	assert.Contains(err.Error(), "export failed")
+
+	// malformed bodies are rejected
+	err = streamDocuments(strings.NewReader(`[]`), "Documents", func(doc json.RawMessage) error { return nil })
+	assert.Contains(err.Error(), "unexpected token")
+}
+
+func TestQueryDocumentsStream(t *testing.T) {
+	assert := assert.New(t)
+	resp := `{  
+		"_rid": "d9RzAJRFKgw=",  
+		"Documents": [  
+		  {"id": "SalesOrder1", "ponumber": "PO18009186470"},  
+		  {"id": "SalesOrder2", "ponumber": "PO15428132599"}  
+		],  
+		"_count": 2  
+	  }`
+	s := ServerFactory(resp, resp)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	query := &QueryWithParameters{
+		Query:      "SELECT * FROM root r WHERE r.ponumber != @po",
+		Parameters: []QueryParameter{{Name: "@po", Value: "PO1"}},
+	}
+	docs := []testDoc{}
+	fn := func(raw json.RawMessage) error {
+		doc := testDoc{}
+		err := json.Unmarshal(raw, &doc)
+		docs = append(docs, doc)
+		return err
+	}
+	_, err := client.QueryDocumentsStream("dbs/d9RzAA==/colls/d9RzAJRFKgw=/", query, fn)
+	assert.Nil(err)
+	s.AssertHeaders(t, HeaderIsQuery)
+	assert.Len(docs, 2)
+	assert.Equal("PO15428132599", docs[1].PONumber)
+
+	// read feed
+	_, err = client.QueryDocumentsStream("dbs/d9RzAA==/colls/d9RzAJRFKgw=/", nil, fn)
+	assert.Nil(err)
+	assert.Len(docs, 4)
+}
@@ -0,0 +1,129 @@
+package gocosmosdb
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Span attribute keys set by the client
+const (
+	TraceAttrDatabase      = "database"
+	TraceAttrCollection    = "collection"
+	TraceAttrOperation     = "operation"
+	TraceAttrActivityID    = "activityId"
+	TraceAttrStatusCode    = "statusCode"
+	TraceAttrSubStatus     = "subStatus"
+	TraceAttrRequestCharge = "requestCharge"
+	TraceAttrAttempt       = "attempt"
+	TraceAttrMethod        = "method"
+)
+
+// Tracer - starts spans, the client starts a span for every logical operation, such as QueryDocuments or
+// the fetch of a query page, and a child span for every HTTP attempt made for it
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span - a span started by a Tracer
+type Span interface {
+	SetAttribute(key string, value interface{})
+	End(err error)
+}
+
+// NoopTracer - a tracer that records nothing, it is used when Config.Tracer is not set
+type NoopTracer struct{}
+
+// Start - returns the context unchanged and a span that does nothing
+func (NoopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) End(err error)                              {}
+
+type traceKey struct{}
+
+// operationTrace - the span of a logical operation and of its current HTTP attempt
+type operationTrace struct {
+	tracer  Tracer
+	ctx     context.Context
+	mu      sync.Mutex
+	attempt Span
+}
+
+// traceFromContext - returns the operation trace of a requests context, if any
+func traceFromContext(ctx context.Context) *operationTrace {
+	if ctx == nil {
+		return nil
+	}
+	t, _ := ctx.Value(traceKey{}).(*operationTrace)
+	return t
+}
+
+// startAttempt - starts the span of an HTTP attempt, ending the previous one if it was left open
+func (t *operationTrace) startAttempt(req *http.Request, attempt int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.attempt != nil {
+		t.attempt.End(nil)
+	}
+	_, span := t.tracer.Start(t.ctx, "HTTP "+req.Method)
+	span.SetAttribute(TraceAttrMethod, req.Method)
+	span.SetAttribute(TraceAttrAttempt, attempt+1)
+	t.attempt = span
+}
+
+// endAttempt - ends the span of the current HTTP attempt
+func (t *operationTrace) endAttempt(resp *http.Response, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.attempt == nil {
+		return
+	}
+	if resp != nil {
+		setResponseAttributes(t.attempt, resp)
+	}
+	t.attempt.End(err)
+	t.attempt = nil
+}
+
+// setResponseAttributes - sets the status and request charge of a response on a span
+func setResponseAttributes(span Span, resp *http.Response) {
+	span.SetAttribute(TraceAttrStatusCode, resp.StatusCode)
+	if sub, err := strconv.Atoi(resp.Header.Get(HeaderSubStatus)); err == nil && sub != 0 {
+		span.SetAttribute(TraceAttrSubStatus, sub)
+	}
+	if charge, err := strconv.ParseFloat(resp.Header.Get(HeaderRequestCharge), 64); err == nil {
+		span.SetAttribute(TraceAttrRequestCharge, charge)
+	}
+}
+
+// tracingInterceptor - wraps every request in an operation span, HTTP attempts are traced by the retry hooks
+func tracingInterceptor(tracer Tracer) Interceptor {
+	return func(r *Request, next Invoker) (*http.Response, error) {
+		ctx, span := tracer.Start(r.context(), r.Operation())
+		span.SetAttribute(TraceAttrOperation, r.Operation())
+		if db := r.Database(); db != "" {
+			span.SetAttribute(TraceAttrDatabase, db)
+		}
+		if coll := r.Collection(); coll != "" {
+			span.SetAttribute(TraceAttrCollection, coll)
+		}
+		if id := r.Header.Get(HeaderActivityID); id != "" {
+			span.SetAttribute(TraceAttrActivityID, id)
+		}
+		t := &operationTrace{tracer: tracer, ctx: ctx}
+		r.rContext = context.WithValue(ctx, traceKey{}, t)
+		resp, err := next(r)
+		t.endAttempt(nil, err)
+		if resp != nil {
+			setResponseAttributes(span, resp)
+		}
+		span.End(err)
+		return resp, err
+	}
+}
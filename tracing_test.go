@@ -0,0 +1,103 @@
+package gocosmosdb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type parentKey struct{}
+
+type recordedSpan struct {
+	name   string
+	parent *recordedSpan
+	attrs  map[string]interface{}
+	ended  bool
+	err    error
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) { s.attrs[key] = value }
+func (s *recordedSpan) End(err error)                              { s.ended, s.err = true, err }
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	parent, _ := ctx.Value(parentKey{}).(*recordedSpan)
+	span := &recordedSpan{name: name, parent: parent, attrs: map[string]interface{}{}}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, parentKey{}, span), span
+}
+
+func TestTracing(t *testing.T) {
+	assert := assert.New(t)
+	attempts := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set(HeaderRequestCharge, "2.5")
+		w.Write([]byte(`{"id": "9"}`))
+	}))
+	defer s.Close()
+	tracer := &recordingTracer{}
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", RetryMax: 1, RetryWaitMax: time.Millisecond, Tracer: tracer}, log)
+
+	_, err := client.ReadDocument("dbs/mydb/colls/mycoll/docs/9", &Document{}, WithContext(context.Background()))
+	assert.Nil(err)
+	assert.Len(tracer.spans, 3)
+	op := tracer.spans[0]
+	assert.Equal("ReadDocument", op.name)
+	assert.Nil(op.parent)
+	assert.True(op.ended)
+	assert.Equal("mydb", op.attrs[TraceAttrDatabase])
+	assert.Equal("mycoll", op.attrs[TraceAttrCollection])
+	assert.Equal("ReadDocument", op.attrs[TraceAttrOperation])
+	assert.Equal(http.StatusOK, op.attrs[TraceAttrStatusCode])
+	assert.Equal(2.5, op.attrs[TraceAttrRequestCharge])
+	assert.NotEmpty(op.attrs[TraceAttrActivityID])
+
+	// one child span per HTTP attempt
+	for i, attempt := range tracer.spans[1:] {
+		assert.Equal("HTTP GET", attempt.name)
+		assert.Equal(op, attempt.parent)
+		assert.Equal(i+1, attempt.attrs[TraceAttrAttempt])
+		assert.True(attempt.ended)
+	}
+	assert.Equal(http.StatusServiceUnavailable, tracer.spans[1].attrs[TraceAttrStatusCode])
+	assert.Equal(http.StatusOK, tracer.spans[2].attrs[TraceAttrStatusCode])
+}
+
+func TestTracingTransportError(t *testing.T) {
+	assert := assert.New(t)
+	tracer := &recordingTracer{}
+	client := New("http://localhost:1", Config{MasterKey: "YXJpZWwNCg==", RetryMax: 0, Tracer: tracer}, log)
+	_, err := client.ReadDatabase("dbs/mydb")
+	assert.NotNil(err)
+	assert.Len(tracer.spans, 2)
+	for _, span := range tracer.spans {
+		assert.True(span.ended)
+	}
+	assert.NotNil(tracer.spans[0].err)
+}
+
+func TestNoopTracer(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(`{"id": "iot2"}`)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", Tracer: NoopTracer{}}, log)
+	db, err := client.ReadDatabase("dbs/qicAAA==")
+	assert.Nil(err)
+	assert.Equal("iot2", db.Id)
+}
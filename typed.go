@@ -0,0 +1,228 @@
+package gocosmosdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"reflect"
+)
+
+// QueryIterator - iterates over the results of a query decoded as T, following the continuation of every page
+//	it := gocosmosdb.Query[User](ctx, client, coll, gocosmosdb.Select().Where(gocosmosdb.Eq("c.active", true)).Build())
+//	for it.Next() {
+//		user := it.Item()
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type QueryIterator[T any] struct {
+	ctx          context.Context
+	client       *CosmosDB
+	coll         string
+	query        *QueryWithParameters
+	opts         []CallOption
+	continuation string
+	sessionToken string
+	activityID   string
+	items        []T
+	pos          int
+	item         T
+	resp         *Response
+	charge       float64
+	started      bool
+	err          error
+}
+
+// Query - returns an iterator over the documents of a collection satisfying the query, or every document of the
+// collection when the query is nil. Pages are read as the iterator advances, the page size is set with Limit.
+func Query[T any](ctx context.Context, c *CosmosDB, coll string, query *QueryWithParameters, opts ...CallOption) *QueryIterator[T] {
+	return &QueryIterator[T]{
+		ctx:    ctx,
+		client: c,
+		coll:   coll,
+		query:  query,
+		opts:   opts,
+	}
+}
+
+// Next - advances to the next result, false once the results are exhausted or reading a page failed
+func (it *QueryIterator[T]) Next() bool {
+	for it.pos >= len(it.items) {
+		if it.err != nil || (it.started && it.continuation == "") {
+			return false
+		}
+		if it.err = it.fetch(); it.err != nil {
+			return false
+		}
+	}
+	it.item = it.items[it.pos]
+	it.pos++
+	return true
+}
+
+// fetch - reads the next page of results
+func (it *QueryIterator[T]) fetch() error {
+	data := struct {
+		Documents []T `json:"Documents,omitempty"`
+		Count     int `json:"_count,omitempty"`
+	}{}
+	opts := it.opts[:len(it.opts):len(it.opts)]
+	if it.ctx != nil {
+		opts = append(opts, WithContext(it.ctx))
+	}
+	if it.started {
+		// every page of the query is part of the same logical operation
+		opts = append(opts, Continuation(it.continuation), SessionToken(it.sessionToken), ActivityID(it.activityID))
+	}
+	var resp *Response
+	var err error
+	if it.query != nil {
+		resp, err = it.client.client.queryWithParameters(it.coll+"docs/", it.query, &data, opts...)
+	} else {
+		resp, err = it.client.client.read(it.coll+"docs/", &data, opts...)
+	}
+	if resp != nil {
+		it.resp = resp
+		if charge, err := resp.GetRUs(); err == nil {
+			it.charge += charge
+		}
+	}
+	if err != nil {
+		// a failed page consumes request units too
+		var reqErr *RequestError
+		if errors.As(err, &reqErr) {
+			it.charge += reqErr.RequestCharge
+		}
+		return err
+	}
+	if !it.started {
+		it.sessionToken, it.activityID, it.started = resp.SessionToken(), resp.ActivityID(), true
+	}
+	it.items, it.pos, it.continuation = data.Documents, 0, resp.Continuation()
+	return nil
+}
+
+// Item - returns the current result
+func (it *QueryIterator[T]) Item() T {
+	return it.item
+}
+
+// Err - returns the error that stopped the iteration, if any
+func (it *QueryIterator[T]) Err() error {
+	return it.err
+}
+
+// Response - returns the response of the page the current result was read from, with its request charge and
+// diagnostics
+func (it *QueryIterator[T]) Response() *Response {
+	return it.resp
+}
+
+// RequestCharge - returns the request units consumed by the pages read so far
+func (it *QueryIterator[T]) RequestCharge() float64 {
+	return it.charge
+}
+
+// Continuation - returns the token of the page after the current one, empty after the last page
+func (it *QueryIterator[T]) Continuation() string {
+	return it.continuation
+}
+
+// All - returns the remaining results as a sequence, an error is yielded last with the zero value of T
+//	for user, err := range gocosmosdb.Query[User](ctx, client, coll, query).All() {
+//		if err != nil {
+//			return err
+//		}
+//	}
+func (it *QueryIterator[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for it.Next() {
+			if !yield(it.Item(), nil) {
+				return
+			}
+		}
+		if it.err != nil {
+			var zero T
+			yield(zero, it.err)
+		}
+	}
+}
+
+// ReadItem - reads a document and decodes it as T
+//	user, resp, err := gocosmosdb.ReadItem[User](ctx, client, "dbs/{db-id}/colls/{coll-id}/docs/{doc-id}")
+func ReadItem[T any](ctx context.Context, c *CosmosDB, link string, opts ...CallOption) (T, *Response, error) {
+	var doc T
+	resp, err := c.client.read(link, &doc, withContext(ctx, opts)...)
+	return doc, resp, err
+}
+
+// CreateItem - creates a document in the collection and returns it as stored, an empty Id is generated
+//	user, resp, err := gocosmosdb.CreateItem(ctx, client, "dbs/{db-id}/colls/{coll-id}/", user)
+func CreateItem[T any](ctx context.Context, c *CosmosDB, coll string, doc T, opts ...CallOption) (T, *Response, error) {
+	var stored T
+	opts = c.prepareDocument(&doc, withContext(ctx, opts))
+	resp, err := c.writeItem(http.MethodPost, coll+"docs/", http.StatusCreated, doc, &stored, opts)
+	return stored, resp, err
+}
+
+// UpsertItem - creates a document or replaces the document with the same id and returns it as stored, an empty Id is
+// generated
+//	user, resp, err := gocosmosdb.UpsertItem(ctx, client, "dbs/{db-id}/colls/{coll-id}/", user)
+func UpsertItem[T any](ctx context.Context, c *CosmosDB, coll string, doc T, opts ...CallOption) (T, *Response, error) {
+	var stored T
+	opts = c.prepareDocument(&doc, append(withContext(ctx, opts), Upsert()))
+	resp, err := c.writeItem(http.MethodPost, coll+"docs/", http.StatusOK, doc, &stored, opts)
+	return stored, resp, err
+}
+
+// ReplaceItem - replaces a document and returns it as stored
+//	user, resp, err := gocosmosdb.ReplaceItem(ctx, client, "dbs/{db-id}/colls/{coll-id}/docs/{doc-id}", user)
+func ReplaceItem[T any](ctx context.Context, c *CosmosDB, link string, doc T, opts ...CallOption) (T, *Response, error) {
+	var stored T
+	opts = c.prepareDocument(&doc, withContext(ctx, opts))
+	resp, err := c.writeItem(http.MethodPut, link, http.StatusOK, doc, &stored, opts)
+	return stored, resp, err
+}
+
+// writeItem - sends a document, unlike create, upsert and replace it accepts documents that are not struct pointers
+func (c *CosmosDB) writeItem(method, link string, status int, doc, ret interface{}, opts []CallOption) (*Response, error) {
+	data, err := c.client.marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return c.client.method(method, link, status, ret, bytes.NewBuffer(data), opts...)
+}
+
+// withContext - appends the context to the options of a request
+func withContext(ctx context.Context, opts []CallOption) []CallOption {
+	if ctx == nil {
+		return opts
+	}
+	return append(opts[:len(opts):len(opts)], WithContext(ctx))
+}
+
+// prepareDocument - generates the Id of a struct document when it is empty and adds its partition key when the
+// client has a PartitionKeyStructField
+func (c *CosmosDB) prepareDocument(doc interface{}, opts []CallOption) []CallOption {
+	v := reflect.ValueOf(doc)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return opts
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return opts
+	}
+	if id := v.FieldByName("Id"); id.IsValid() && id.CanSet() && id.Kind() == reflect.String && id.String() == "" {
+		id.SetString(genId())
+	}
+	if c.Config.PartitionKeyStructField != "" {
+		if partKey := v.FieldByName(c.Config.PartitionKeyStructField); partKey.IsValid() && partKey.CanInterface() {
+			opts = append(opts[:len(opts):len(opts)], PartitionKey(partKey.Interface()))
+		}
+	}
+	return opts
+}
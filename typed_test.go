@@ -0,0 +1,156 @@
+package gocosmosdb
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type typedUser struct {
+	Document
+	Name string `json:"name"`
+}
+
+func TestQueryIterator(t *testing.T) {
+	assert := assert.New(t)
+	s, requests := pagedServer(
+		`{"Documents": [{"id": "1", "name": "ann"}, {"id": "2", "name": "bob"}]}`,
+		`{"Documents": []}`,
+		`{"Documents": [{"id": "3", "name": "cat"}]}`,
+	)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	it := Query[typedUser](context.Background(), client, "dbs/db/colls/coll/", Select().Build(), Limit(2))
+	names := []string{}
+	for it.Next() {
+		names = append(names, it.Item().Name)
+		assert.NotNil(it.Response().Diagnostics)
+	}
+	assert.Nil(it.Err())
+	assert.Equal([]string{"ann", "bob", "cat"}, names)
+	assert.Equal(7.5, it.RequestCharge())
+	assert.Equal("", it.Continuation())
+	assert.Len(*requests, 3)
+	last := (*requests)[2]
+	assert.Equal("2", last.Header.Get(HeaderContinuation))
+	assert.Equal("0:1", last.Header.Get(HeaderSessionToken))
+	assert.Equal((*requests)[0].Header.Get(HeaderActivityID), last.Header.Get(HeaderActivityID))
+	assert.False(it.Next())
+}
+
+func TestQueryIteratorPages(t *testing.T) {
+	assert := assert.New(t)
+	s, _ := pagedServer(`{"Documents": [{"id": "1"}]}`, `{"Documents": [{"id": "2"}]}`, `{"Documents": [{"id": "3"}]}`)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	bodies := []string{}
+	client.Use(func(r *Request, next Invoker) (*http.Response, error) {
+		body, _ := readBody(r.Request)
+		bodies = append(bodies, string(body))
+		return next(r)
+	})
+	query := Select().Where(Eq(`c.say "hi"`, "x")).Build()
+	it := Query[typedUser](context.Background(), client, "dbs/db/colls/coll/", query)
+	for it.Next() {
+	}
+	assert.Nil(it.Err())
+	// every page sends the query as built
+	assert.Len(bodies, 3)
+	assert.Equal(bodies[0], bodies[1])
+	assert.Equal(bodies[0], bodies[2])
+	assert.Contains(bodies[0], `c['say \"hi\"']`)
+	assert.Equal(`SELECT * FROM c WHERE c['say "hi"'] = @p0`, query.Query)
+
+	// the request charge of a failed page is counted
+	client = New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	client.Use(func(r *Request, next Invoker) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{HeaderRequestCharge: {"1.5"}},
+			Body:       ioutil.NopCloser(strings.NewReader(`{"code": "TooManyRequests"}`)),
+		}, nil
+	})
+	it = Query[typedUser](context.Background(), client, "dbs/db/colls/coll/", query)
+	assert.False(it.Next())
+	assert.True(IsThrottled(it.Err()))
+	assert.Equal(1.5, it.RequestCharge())
+}
+
+func TestQueryIteratorAll(t *testing.T) {
+	assert := assert.New(t)
+	s, _ := pagedServer(`{"Documents": [{"id": "1"}, {"id": "2"}]}`, `{"Documents": [{"id": "3"}]}`)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	ids := []string{}
+	for doc, err := range Query[map[string]interface{}](context.Background(), client, "dbs/db/colls/coll/", nil).All() {
+		assert.Nil(err)
+		ids = append(ids, doc["id"].(string))
+		if len(ids) == 2 {
+			break
+		}
+	}
+	assert.Equal([]string{"1", "2"}, ids)
+
+	e := ServerFactory(500, 500)
+	defer e.Close()
+	client = New(e.URL, Config{MasterKey: "YXJpZWwNCg==", RetryMax: 1}, log)
+	errs := 0
+	for _, err := range Query[typedUser](context.Background(), client, "dbs/db/colls/coll/", Select().Build()).All() {
+		assert.NotNil(err)
+		errs++
+	}
+	assert.Equal(1, errs)
+}
+
+func TestQueryIteratorContext(t *testing.T) {
+	assert := assert.New(t)
+	s, requests := pagedServer(`{"Documents": [{"id": "1"}]}`)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg=="}, log)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	it := Query[typedUser](ctx, client, "dbs/db/colls/coll/", Select().Build())
+	assert.False(it.Next())
+	assert.NotNil(it.Err())
+	assert.Len(*requests, 0)
+}
+
+func TestTypedPointOperations(t *testing.T) {
+	assert := assert.New(t)
+	s := ServerFactory(
+		`{"id": "1", "name": "ann", "_etag": "e1"}`,
+		`{"id": "2", "name": "bob", "_etag": "e2"}`,
+		`{"id": "3", "name": "cat", "_etag": "e3"}`,
+		`{"id": "3", "name": "cal", "_etag": "e4"}`,
+	)
+	defer s.Close()
+	client := New(s.URL, Config{MasterKey: "YXJpZWwNCg==", PartitionKeyStructField: "Name"}, log)
+	ctx := context.Background()
+
+	user, resp, err := ReadItem[typedUser](ctx, client, "dbs/db/colls/coll/docs/1")
+	assert.Nil(err)
+	assert.NotNil(resp)
+	assert.Equal("ann", user.Name)
+
+	user, _, err = UpsertItem(ctx, client, "dbs/db/colls/coll/", typedUser{Name: "bob"})
+	assert.Nil(err)
+	assert.Equal("e2", user.Etag)
+	assert.Equal(`["bob"]`, s.Header.Get(HeaderPartitionKey))
+	assert.Contains(s.Body, `"id":"`)
+	assert.NotContains(s.Body, `"id":""`)
+	assert.Equal("true", s.Header.Get(HeaderUpsert))
+
+	s.SetStatus(http.StatusCreated)
+	ptr, _, err := CreateItem(ctx, client, "dbs/db/colls/coll/", &typedUser{Document: Document{Resource: Resource{Id: "3"}}, Name: "cat"})
+	assert.Nil(err)
+	assert.Equal("e3", ptr.Etag)
+
+	s.SetStatus(http.StatusOK)
+	doc, _, err := ReplaceItem(ctx, client, "dbs/db/colls/coll/docs/3", map[string]interface{}{"id": "3", "name": "cal"})
+	assert.Nil(err)
+	assert.Equal("cal", doc["name"])
+}
@@ -29,7 +29,7 @@ type IndexingPolicy struct {
 		Indexes []struct {
 			DataType  string `json:"dataType,omitempty"`
 			Kind      string `json:"kind,omitempty"`
-			Precision int    `json:"precision,omitempty`
+			Precision int    `json:"precision,omitempty"`
 		} `json:"indexes,omitempty"`
 		Path string `json:"path,omitempty"`
 	} `json:"includedPaths,omitempty"`
@@ -128,8 +128,10 @@ type PagableQuery struct {
 	client       *CosmosDB
 	coll         string
 	query        *QueryWithParameters
-	sessionToken CallOption
-	continuation CallOption
+	hash         string
+	sessionToken string
+	continuation string
+	activityID   string
 	limit        CallOption
 	offset       int64
 	docs         interface{}
@@ -44,6 +44,8 @@ func stringify(body interface{}) (bt []byte, err error) {
 		bt = []byte(t)
 	case []byte:
 		bt = t
+	case json.RawMessage:
+		bt = t
 	default:
 		bt, err = json.Marshal(t)
 	}